@@ -0,0 +1,281 @@
+package ohmyglob
+
+import (
+	"encoding/binary"
+	"regexp"
+	"regexp/syntax"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"unicode/utf8"
+)
+
+// MaxAutomatonStates is the maximum number of states a GlobSet's automaton will cache. If more are needed, the cache is
+// discarded and rebuilt as matching continues.
+var MaxAutomatonStates = 10000
+
+// automatable is implemented by Globs whose matching can be simulated by a setAutomaton
+type automatable interface {
+	// Returns the Glob's regular expression, and whether it matches exactly the same input as the Glob (if not, it
+	// matches a superset of that input, and its matches must be verified)
+	automatonRegexp() (*regexp.Regexp, bool)
+}
+
+func (g *globImpl) automatonRegexp() (*regexp.Regexp, bool) {
+	return g.Regexp, true
+}
+
+func (g *negatedGroupGlob) automatonRegexp() (*regexp.Regexp, bool) {
+	return g.Regexp, false
+}
+
+// setAutomaton matches many regular expressions at once, in a single pass over the input. The expressions' compiled
+// programs share a combined instruction space, and are run together as a lazily-built DFA: each state is the set of
+// instructions that are live after reading some input, and is computed the first time it is reached, then cached.
+type setAutomaton struct {
+	progs []*syntax.Prog
+	// The id of each program's first instruction
+	offsets []int
+	// The program that each instruction id belongs to
+	owners []int
+	// The programs which are not anchored to the start of the input, and so are started at every position
+	unanchored []int
+
+	// Guards the creation of states, and transitions on non-ASCII runes
+	mu     sync.Mutex
+	start  atomic.Pointer[automatonState]
+	states map[string]*automatonState
+}
+
+// automatonState is a state of a setAutomaton's DFA
+type automatonState struct {
+	// The sorted ids of the live instructions: those which consume input, and empty-width assertions which were not
+	// satisfied when the state was reached (and which may be satisfied at the end of the input)
+	ids []int
+	// The programs which matched on reaching the state
+	matches []int
+	// The programs which match if the input ends in the state; computed when first needed
+	endMatches atomic.Pointer[[]int]
+	// The states reached by consuming each rune
+	ascii [utf8.RuneSelf]atomic.Pointer[automatonState]
+	other map[rune]*automatonState
+}
+
+// newSetAutomaton compiles the regular expressions into a setAutomaton
+func newSetAutomaton(res []*regexp.Regexp) (*setAutomaton, error) {
+	a := &setAutomaton{
+		progs:   make([]*syntax.Prog, len(res)),
+		offsets: make([]int, len(res)),
+		states:  make(map[string]*automatonState),
+	}
+	for i, re := range res {
+		parsed, err := syntax.Parse(re.String(), syntax.Perl)
+		if err != nil {
+			return nil, err
+		}
+		prog, err := syntax.Compile(parsed.Simplify())
+		if err != nil {
+			return nil, err
+		}
+
+		a.progs[i] = prog
+		a.offsets[i] = len(a.owners)
+		for range prog.Inst {
+			a.owners = append(a.owners, i)
+		}
+		if prog.StartCond()&syntax.EmptyBeginText == 0 {
+			a.unanchored = append(a.unanchored, i)
+		}
+	}
+	return a, nil
+}
+
+// Returns the instruction with the given id, and the program it belongs to
+func (a *setAutomaton) inst(id int) (*syntax.Inst, int) {
+	owner := a.owners[id]
+	return &a.progs[owner].Inst[id-a.offsets[owner]], owner
+}
+
+// Follows all of the instructions reachable from the roots without consuming input, returning the sorted ids of the
+// live instructions that were reached, and the programs that matched
+func (a *setAutomaton) closure(roots []int, flag syntax.EmptyOp) ([]int, []int) {
+	var ids, matches []int
+	visited := make(map[int]bool, len(roots)*2)
+	stack := append(make([]int, 0, len(roots)), roots...)
+	for len(stack) > 0 {
+		id := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if visited[id] {
+			continue
+		}
+		visited[id] = true
+
+		inst, owner := a.inst(id)
+		offset := a.offsets[owner]
+		switch inst.Op {
+		case syntax.InstAlt, syntax.InstAltMatch:
+			stack = append(stack, offset+int(inst.Arg), offset+int(inst.Out))
+		case syntax.InstNop, syntax.InstCapture:
+			stack = append(stack, offset+int(inst.Out))
+		case syntax.InstEmptyWidth:
+			if syntax.EmptyOp(inst.Arg)&^flag == 0 {
+				stack = append(stack, offset+int(inst.Out))
+			} else {
+				ids = append(ids, id)
+			}
+		case syntax.InstMatch:
+			matches = append(matches, owner)
+		case syntax.InstRune, syntax.InstRune1, syntax.InstRuneAny, syntax.InstRuneAnyNotNL:
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+	return ids, matches
+}
+
+// Returns the cached state for the closure of the roots, creating it if needed. The lock must be held.
+func (a *setAutomaton) state(roots []int, flag syntax.EmptyOp) *automatonState {
+	ids, matches := a.closure(roots, flag)
+	keyBuf := make([]byte, 0, len(ids)*3+len(matches)*3+1)
+	for _, id := range ids {
+		keyBuf = binary.AppendUvarint(keyBuf, uint64(id))
+	}
+	// The matches are part of the key, as states with the same live instructions may have been reached differently
+	keyBuf = append(keyBuf, 0xff)
+	for _, match := range matches {
+		keyBuf = binary.AppendUvarint(keyBuf, uint64(match))
+	}
+	key := string(keyBuf)
+
+	if s, ok := a.states[key]; ok {
+		return s
+	}
+	if len(a.states) >= MaxAutomatonStates {
+		// Discard the cache; states which are already in use remain valid
+		a.states = make(map[string]*automatonState)
+		a.start.Store(nil)
+	}
+	s := &automatonState{
+		ids:     ids,
+		matches: matches,
+	}
+	a.states[key] = s
+	return s
+}
+
+// Returns the state reached from s by consuming the rune r
+func (a *setAutomaton) step(s *automatonState, r rune) *automatonState {
+	if r >= 0 && r < utf8.RuneSelf {
+		if next := s.ascii[r].Load(); next != nil {
+			return next
+		}
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if next, ok := s.other[r]; ok {
+		return next
+	}
+
+	roots := make([]int, 0, len(s.ids)+len(a.unanchored))
+	for _, id := range s.ids {
+		inst, owner := a.inst(id)
+		matches := false
+		switch inst.Op {
+		case syntax.InstRune, syntax.InstRune1:
+			matches = inst.MatchRune(r)
+		case syntax.InstRuneAny:
+			matches = true
+		case syntax.InstRuneAnyNotNL:
+			matches = r != '\n'
+		}
+		if matches {
+			roots = append(roots, a.offsets[owner]+int(inst.Out))
+		}
+	}
+	for _, i := range a.unanchored {
+		roots = append(roots, a.offsets[i]+a.progs[i].Start)
+	}
+
+	next := a.state(roots, 0)
+	if r >= 0 && r < utf8.RuneSelf {
+		s.ascii[r].Store(next)
+	} else {
+		if s.other == nil {
+			s.other = make(map[rune]*automatonState)
+		}
+		s.other[r] = next
+	}
+	return next
+}
+
+// Returns the programs which match if the input ends in the state s
+func (a *setAutomaton) endMatches(s *automatonState) []int {
+	if matches := s.endMatches.Load(); matches != nil {
+		return *matches
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	_, matches := a.closure(s.ids, syntax.EmptyEndText|syntax.EmptyEndLine)
+	s.endMatches.Store(&matches)
+	return matches
+}
+
+// Returns the start state
+func (a *setAutomaton) startState() *automatonState {
+	state := a.start.Load()
+	if state == nil {
+		a.mu.Lock()
+		roots := make([]int, len(a.progs))
+		for i, prog := range a.progs {
+			roots[i] = a.offsets[i] + prog.Start
+		}
+		state = a.state(roots, syntax.EmptyBeginText|syntax.EmptyBeginLine)
+		a.start.Store(state)
+		a.mu.Unlock()
+	}
+	return state
+}
+
+// Returns the state reached by consuming the input, and the programs which matched before its end
+func (a *setAutomaton) run(s string) (*automatonState, []int) {
+	state := a.startState()
+	var matches []int
+	for _, r := range s {
+		matches = append(matches, state.matches...)
+		state = a.step(state, r)
+	}
+	return state, append(matches, state.matches...)
+}
+
+// match returns the sorted indices of the programs which match the input
+func (a *setAutomaton) match(s string) []int {
+	state, matches := a.run(s)
+	matches = append(matches, a.endMatches(state)...)
+	// Programs which aren't anchored to the end of the input may have matched more than once
+	return dedupeSorted(matches)
+}
+
+// prefixMatches returns the programs which could match some input beginning with the prefix: those with live
+// instructions after consuming it, and those which matched before its end (which, as they are not anchored to the end
+// of the input, match anything that follows). The result may contain duplicates.
+func (a *setAutomaton) prefixMatches(prefix string) []int {
+	state, matches := a.run(prefix)
+	for _, id := range state.ids {
+		matches = append(matches, a.owners[id])
+	}
+	return matches
+}
+
+// dedupeSorted sorts the indices, and removes any duplicates
+func dedupeSorted(matches []int) []int {
+	sort.Ints(matches)
+	result := matches[:0]
+	for i, match := range matches {
+		if i == 0 || match != matches[i-1] {
+			result = append(result, match)
+		}
+	}
+	return result
+}
@@ -0,0 +1,520 @@
+package ohmyglob
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxBraceSequenceLength is the maximum number of values a sequence expression (eg. {1..10}) may produce. Patterns
+// containing longer sequences are rejected, rather than compiling to enormous regular expressions.
+var MaxBraceSequenceLength = 10000
+
+// MaxBraceExpansions is the maximum number of patterns ExpandBraces may expand a pattern to. The number of expansions
+// is the product of the numbers of alternatives of the brace expressions, so grows quickly; patterns with more are
+// rejected, rather than exhausting memory.
+var MaxBraceExpansions = 10000
+
+var (
+	numericSequenceRegex   = regexp.MustCompile(`^([-+]?[0-9]+)\.\.([-+]?[0-9]+)(?:\.\.([-+]?[0-9]+))?$`)
+	characterSequenceRegex = regexp.MustCompile(`^(.)\.\.(.)(?:\.\.([-+]?[0-9]+))?$`)
+)
+
+// braceNode is a component of a pattern parsed for brace expansion: either a run of (brace-free) pattern text, or a
+// brace expression with a set of alternatives, each of which is itself a sequence of nodes
+type braceNode struct {
+	text         string
+	alternatives [][]*braceNode
+	// If the node is a brace expression, the byte offset of its opening brace
+	offset int
+	// If the node is text consisting of a single literal token, the unescaped literal
+	literal string
+	// If the node is text, the capturing tokens within it
+	captures []braceCapture
+}
+
+// braceCapture locates a capturing token (a wildcard or bracket expression) within the text of a brace node or an
+// expansion
+type braceCapture struct {
+	// The byte offset of the token within the text
+	at int
+	// The byte offset of the token within the pattern
+	origin int
+}
+
+// braceExpansion is a brace-free pattern produced by brace expansion, with the capturing tokens within it
+type braceExpansion struct {
+	pattern  string
+	captures []braceCapture
+}
+
+// parseBraceSequence consumes tokens until the end of the input or the end of the current brace alternative, returning
+// the nodes that were parsed and the type of the token that terminated the sequence (tcUnknown at the end of input)
+func parseBraceSequence(tokeniser *globTokeniser, options *Options) ([]*braceNode, tc, bool, error) {
+	nodes := make([]*braceNode, 0, 4)
+	textBuf := new(bytes.Buffer)
+	textTokens := make([]string, 0, 4)
+	textTypes := make([]tc, 0, 4)
+	var captures []braceCapture
+	hasBraces := false
+
+	flushText := func() {
+		if textBuf.Len() > 0 {
+			node := &braceNode{text: textBuf.String(), captures: captures}
+			if len(textTokens) == 1 && textTypes[0] == tcLiteral {
+				node.literal = textTokens[0]
+			}
+			nodes = append(nodes, node)
+			textBuf.Reset()
+			textTokens = textTokens[:0]
+			textTypes = textTypes[:0]
+			captures = nil
+		}
+	}
+
+	for tokeniser.Scan() {
+		token, tokenType := tokeniser.Token()
+		switch tokenType {
+		case tcBraceOpen:
+			hasBraces = true
+			flushText()
+			offset := tokeniser.Offset()
+			node := &braceNode{offset: offset}
+			for {
+				alternative, terminator, _, err := parseBraceSequence(tokeniser, options)
+				if err != nil {
+					return nil, tcUnknown, hasBraces, err
+				}
+				node.alternatives = append(node.alternatives, alternative)
+				if terminator != tcBraceSeparator {
+					break
+				}
+			}
+			if len(node.alternatives) == 1 && len(node.alternatives[0]) == 1 && node.alternatives[0][0].literal != "" {
+				values, isSequence, err := expandSequenceExpression(node.alternatives[0][0].literal, offset)
+				if err != nil {
+					return nil, tcUnknown, hasBraces, err
+				}
+				if isSequence {
+					node.alternatives = make([][]*braceNode, len(values))
+					for i, value := range values {
+						node.alternatives[i] = []*braceNode{{text: EscapeGlobComponent(value, options)}}
+					}
+				}
+			}
+			nodes = append(nodes, node)
+		case tcBraceSeparator, tcBraceClose:
+			flushText()
+			return nodes, tokenType, hasBraces, nil
+		case tcLiteral:
+			textBuf.WriteString(EscapeGlobComponent(token, options))
+			textTokens = append(textTokens, token)
+			textTypes = append(textTypes, tokenType)
+		default:
+			if isCapturing(tokenType) {
+				captures = append(captures, braceCapture{at: textBuf.Len(), origin: tokeniser.Offset()})
+			}
+			textBuf.WriteString(token)
+			textTokens = append(textTokens, token)
+			textTypes = append(textTypes, tokenType)
+		}
+	}
+	if err := tokeniser.Err(); err != nil {
+		return nil, tcUnknown, hasBraces, err
+	}
+
+	flushText()
+	return nodes, tcUnknown, hasBraces, nil
+}
+
+// parseSequenceStep parses the (optional) step of a sequence expression; the sign of the step is ignored, as the
+// direction of the sequence is determined by its endpoints
+func parseSequenceStep(expression, step string, offset int) (int64, error) {
+	if step == "" {
+		return 1, nil
+	}
+	parsed, err := strconv.ParseInt(step, 10, 64)
+	if err != nil || parsed == 0 {
+		return 0, newPatternError(ErrInvalidSequence, offset, "{"+expression+"}",
+			"invalid step \"%s\" in sequence expression \"{%s}\"", step, expression)
+	}
+	if parsed < 0 {
+		parsed = -parsed
+	}
+	return parsed, nil
+}
+
+// Returns whether the textual representation of an integer is zero-padded (eg. "007" or "-01")
+func isZeroPadded(number string) bool {
+	number = strings.TrimLeft(number, "-+")
+	return len(number) > 1 && number[0] == '0'
+}
+
+// expandSequenceExpression returns the values produced by a sequence expression (the contents of a brace expression
+// such as {1..10}, {01..10}, {0..100..10} or {a..f}). If the expression is not a sequence expression, the second
+// return value is false. The offset of the opening brace is used to report any error.
+func expandSequenceExpression(expression string, offset int) ([]string, bool, error) {
+	var start, end int64
+	var stepPart string
+	var format func(value int64) string
+
+	if parts := numericSequenceRegex.FindStringSubmatch(expression); parts != nil {
+		var startErr, endErr error
+		start, startErr = strconv.ParseInt(parts[1], 10, 64)
+		end, endErr = strconv.ParseInt(parts[2], 10, 64)
+		if startErr != nil || endErr != nil {
+			return nil, true, newPatternError(ErrInvalidSequence, offset, "{"+expression+"}",
+				"sequence expression \"{%s}\" is out of range", expression)
+		}
+		stepPart = parts[3]
+
+		// As with bash, if either endpoint is zero-padded, all values are padded to the width of the widest endpoint
+		width := 0
+		if isZeroPadded(parts[1]) || isZeroPadded(parts[2]) {
+			width = len(strings.TrimLeft(parts[1], "+"))
+			if w := len(strings.TrimLeft(parts[2], "+")); w > width {
+				width = w
+			}
+		}
+		format = func(value int64) string {
+			return fmt.Sprintf("%0*d", width, value)
+		}
+	} else if parts := characterSequenceRegex.FindStringSubmatch(expression); parts != nil {
+		startRune, _ := utf8.DecodeRuneInString(parts[1])
+		endRune, _ := utf8.DecodeRuneInString(parts[2])
+		start, end = int64(startRune), int64(endRune)
+		stepPart = parts[3]
+		format = func(value int64) string {
+			return string(rune(value))
+		}
+	} else {
+		return nil, false, nil
+	}
+
+	step, err := parseSequenceStep(expression, stepPart, offset)
+	if err != nil {
+		return nil, true, err
+	}
+	// The length is calculated with floats so that the span of extreme endpoints can't overflow
+	length := math.Floor(math.Abs(float64(end)-float64(start))/float64(step)) + 1
+	if length > float64(MaxBraceSequenceLength) {
+		return nil, true, newPatternError(ErrSequenceTooLong, offset, "{"+expression+"}",
+			"sequence expression \"{%s}\" produces %.0f values; at most %d are allowed", expression, length,
+			MaxBraceSequenceLength)
+	}
+
+	if end < start {
+		step = -step
+	}
+	values := make([]string, int(length))
+	for i := range values {
+		values[i] = format(start + int64(i)*step)
+	}
+	return values, true, nil
+}
+
+// expandBraceNodes returns every brace-free pattern that can be produced from the sequence of nodes, failing if there
+// are more than MaxBraceExpansions
+func expandBraceNodes(nodes []*braceNode) ([]braceExpansion, error) {
+	results := []braceExpansion{{}}
+	for _, node := range nodes {
+		var expansions []braceExpansion
+		if node.alternatives == nil {
+			expansions = []braceExpansion{{pattern: node.text, captures: node.captures}}
+		} else {
+			for _, alternative := range node.alternatives {
+				alternativeExpansions, err := expandBraceNodes(alternative)
+				if err != nil {
+					return nil, err
+				}
+				expansions = append(expansions, alternativeExpansions...)
+				if len(expansions) > MaxBraceExpansions {
+					return nil, tooManyExpansionsError(node)
+				}
+			}
+		}
+
+		// The product is calculated with floats so that it can't overflow
+		if float64(len(results))*float64(len(expansions)) > float64(MaxBraceExpansions) {
+			return nil, tooManyExpansionsError(node)
+		}
+		product := make([]braceExpansion, 0, len(results)*len(expansions))
+		for _, prefix := range results {
+			for _, expansion := range expansions {
+				captures := make([]braceCapture, 0, len(prefix.captures)+len(expansion.captures))
+				captures = append(captures, prefix.captures...)
+				for _, capture := range expansion.captures {
+					at := len(prefix.pattern) + capture.at
+					captures = append(captures, braceCapture{at: at, origin: capture.origin})
+				}
+				pattern := prefix.pattern + expansion.pattern
+				product = append(product, braceExpansion{pattern: pattern, captures: captures})
+			}
+		}
+		results = product
+	}
+	return results, nil
+}
+
+// Returns the error for a pattern whose expansions exceeded MaxBraceExpansions at the node
+func tooManyExpansionsError(node *braceNode) error {
+	return newPatternError(ErrTooManyExpansions, node.offset, "{", "brace expressions produce more than %d expansions",
+		MaxBraceExpansions)
+}
+
+// starEnds summarises the ends of the expansions of a sequence of tokens, for bracesGlueStars
+type starEnds struct {
+	// Whether the sequence can expand to nothing
+	empty bool
+	// Whether the sequence can expand to a pattern which begins or ends with a star
+	starts, ends bool
+	// Whether the sequence can end with a star by way of a brace expression, rather than only with a star of its own
+	endsBraced bool
+	// Whether an expansion of the sequence has two stars next to each other by way of a brace expression
+	glues bool
+}
+
+// bracesGlueStars returns whether any expansion of a pattern (given as the types of its tokens) places two stars next
+// to each other which aren't next to each other in the pattern (eg. "*{,/}*" expands to "**"). Such stars form a
+// globstar, so the expansion matches differently from the pattern.
+func bracesGlueStars(tokenTypes []tc) bool {
+	ends, _, _ := starEndsOf(tokenTypes, 0)
+	return ends.glues
+}
+
+// starEndsOf summarises the sequence of tokens beginning at the index and ending at the end of the pattern or of the
+// current brace alternative, returning the index after the sequence and the type of the token that terminated it
+func starEndsOf(tokenTypes []tc, i int) (starEnds, int, tc) {
+	result := starEnds{empty: true}
+	for i < len(tokenTypes) {
+		tokenType := tokenTypes[i]
+		i++
+		item, braced := starEnds{}, false
+		switch tokenType {
+		case tcBraceSeparator, tcBraceClose:
+			return result, i, tokenType
+		case tcBraceOpen:
+			braced = true
+			for {
+				var alternative starEnds
+				var terminator tc
+				alternative, i, terminator = starEndsOf(tokenTypes, i)
+				item.empty = item.empty || alternative.empty
+				item.starts = item.starts || alternative.starts
+				item.ends = item.ends || alternative.ends
+				item.glues = item.glues || alternative.glues
+				if terminator != tcBraceSeparator {
+					break
+				}
+			}
+		default:
+			item.starts = tokenType == tcStar || tokenType == tcGlobStar
+			item.ends = item.starts
+		}
+
+		result.glues = result.glues || item.glues || (result.ends && item.starts && (result.endsBraced || braced))
+		if result.empty {
+			result.starts = result.starts || item.starts
+		}
+		if item.empty {
+			// A star before the (empty) brace expression can end the sequence, as can one within it
+			result.ends = result.ends || item.ends
+			result.endsBraced = result.ends
+		} else {
+			result.ends, result.endsBraced = item.ends, item.ends && braced
+		}
+		result.empty = result.empty && item.empty
+	}
+	return result, i, tcUnknown
+}
+
+// ExpandBraces returns the brace-free patterns that are together equivalent to the passed pattern, in the order in
+// which a shell would expand them. Brace expressions may be nested, and may contain empty alternatives (eg.
+// "foo{,.bak}"). As in bash, braces which aren't closed, or which contain neither a comma nor a sequence expression
+// (eg. "{a}"), are literal. A pattern that contains no brace expressions is returned unchanged, and one which would
+// expand to more than MaxBraceExpansions patterns is rejected. If no options are given, the DefaultOptions are used.
+// Any error is a *PatternError.
+func ExpandBraces(pattern string, options *Options) ([]string, error) {
+	if options == nil {
+		options = DefaultOptions
+	}
+
+	// Negation prefixes apply to every expansion
+	negationPrefix, body := splitNegation(pattern, options)
+
+	tokeniser := newGlobTokeniser(strings.NewReader(body), options)
+	nodes, _, hasBraces, err := parseBraceSequence(tokeniser, options)
+	if err != nil {
+		return nil, completePatternError(err, pattern, len(negationPrefix))
+	}
+	if !hasBraces {
+		return []string{pattern}, nil
+	}
+
+	expansions, err := expandBraceNodes(nodes)
+	if err != nil {
+		return nil, completePatternError(err, pattern, len(negationPrefix))
+	}
+	patterns := make([]string, len(expansions))
+	for i, expansion := range expansions {
+		patterns[i] = negationPrefix + expansion.pattern
+	}
+	return patterns, nil
+}
+
+// expandedGlob is a Glob whose pattern has a globstar adjoining a brace expression (eg. "a/{**,x}/b"). Which of the
+// separators around a globstar it consumes depends on the tokens next to it, which differ between the expansions of
+// the brace expression, so the Glob's regular expression is the alternation of those of its expansions. Each
+// expansion has its own groups within the alternation, which are mapped back to the groups of the pattern.
+type expandedGlob struct {
+	*globImpl
+	// The names of the pattern's groups
+	subexpNames []string
+	// For each group of the alternation, the pattern's groups whose text it captures
+	groups [][]int
+}
+
+// newExpandedGlob replaces the regular expression of the Glob with the alternation of those of its expansions. The
+// remainder is the pattern without its negation prefixes, which begins at the offset within the pattern.
+func newExpandedGlob(glob *globImpl, remainder string, offset int) (*expandedGlob, error) {
+	state := glob.parserState
+	// The pattern itself has been checked for strictness, and its expansions may glue wildcards together
+	options := *state.options
+	options.Strict = false
+
+	tokeniser := newGlobTokeniser(strings.NewReader(remainder), &options)
+	tokeniser.offset = offset
+	nodes, _, _, err := parseBraceSequence(tokeniser, &options)
+	if err != nil {
+		return nil, err
+	}
+	expansions, err := expandBraceNodes(nodes)
+	if err != nil {
+		return nil, err
+	}
+
+	// The pattern's groups, by the offset of their tokens
+	patternGroups := make(map[int]int)
+	for _, t := range state.processedTokens {
+		if isCapturing(t.tokenType) {
+			patternGroups[t.offset] = len(patternGroups) + 1
+		}
+	}
+
+	g := &expandedGlob{
+		globImpl:    glob,
+		subexpNames: glob.Regexp.SubexpNames(),
+		groups:      [][]int{{0}},
+	}
+	regexBuf := new(bytes.Buffer)
+	regexBuf.WriteString(regexFlags(&options))
+	if options.MatchAtStart {
+		regexBuf.WriteRune('^')
+	}
+	regexBuf.WriteString("(?:")
+	for i, expansion := range expansions {
+		if i > 0 {
+			regexBuf.WriteRune('|')
+		}
+		expansionState := &parserState{
+			options:          &options,
+			escapedSeparator: state.escapedSeparator,
+			excludedRunes:    state.excludedRunes,
+			processedTokens:  make([]processedToken, 0, 10),
+			captureNames:     make(map[string]bool),
+		}
+		expansionGlob := &globImpl{parserState: expansionState, options: &options}
+		err = parseTokens(expansionGlob, newGlobTokeniser(strings.NewReader(expansion.pattern), &options))
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range expansionState.processedTokens {
+			regexBuf.Write(t.contents.Bytes())
+			if isCapturing(t.tokenType) {
+				offsets := append([]int{t.offset}, t.mergedOffsets...)
+				g.groups = append(g.groups, expansionGroups(expansion, offsets, patternGroups))
+			}
+		}
+	}
+	regexBuf.WriteRune(')')
+	if options.MatchAtEnd {
+		regexBuf.WriteRune('$')
+	}
+
+	regexString := regexBuf.String()
+	Logger.Tracef("[ohmyglob:Glob] Compiled the expansions of \"%s\" to regex `%s`", glob.globPattern, regexString)
+	re, err := regexp.Compile(regexString)
+	if err != nil {
+		return nil, err
+	}
+	glob.Regexp = re
+	return g, nil
+}
+
+// expansionGroups returns the pattern's groups for the capturing token at the offsets within the expansion (a globstar
+// has more than one offset if others were merged into it)
+func expansionGroups(expansion braceExpansion, offsets []int, patternGroups map[int]int) []int {
+	var groups []int
+	for _, offset := range offsets {
+		for _, capture := range expansion.captures {
+			if capture.at == offset {
+				if group, ok := patternGroups[capture.origin]; ok {
+					groups = append(groups, group)
+				}
+			}
+		}
+	}
+	return groups
+}
+
+// patternSubmatchIndex maps the submatch indices of a match of the alternation to those of the pattern's groups
+func (g *expandedGlob) patternSubmatchIndex(loc []int) []int {
+	if loc == nil {
+		return nil
+	}
+	result := make([]int, 2*len(g.subexpNames))
+	for i := range result {
+		result[i] = -1
+	}
+	for i, groups := range g.groups {
+		if loc[2*i] < 0 {
+			continue
+		}
+		for _, group := range groups {
+			result[2*group], result[2*group+1] = loc[2*i], loc[2*i+1]
+		}
+	}
+	return result
+}
+
+func (g *expandedGlob) SubexpNames() []string {
+	return g.subexpNames
+}
+
+func (g *expandedGlob) NumSubexp() int {
+	return len(g.subexpNames) - 1
+}
+
+func (g *expandedGlob) FindStringSubmatchIndex(s string) []int {
+	return g.patternSubmatchIndex(g.Regexp.FindStringSubmatchIndex(s))
+}
+
+func (g *expandedGlob) FindSubmatchIndex(b []byte) []int {
+	return g.patternSubmatchIndex(g.Regexp.FindSubmatchIndex(b))
+}
+
+func (g *expandedGlob) FindStringSubmatch(s string) []string {
+	return submatchStrings(s, g.FindStringSubmatchIndex(s))
+}
+
+func (g *expandedGlob) FindSubmatch(b []byte) [][]byte {
+	return submatchBytes(b, g.FindSubmatchIndex(b))
+}
+
+func (g *expandedGlob) Captures(s string) map[string]string {
+	return namedCaptures(g.subexpNames, g.FindStringSubmatch(s))
+}
@@ -0,0 +1,193 @@
+package ohmyglob
+
+import (
+	"testing"
+
+	"github.com/obeattie/ohmyglob/internal/equivalence"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpandBraces(t *testing.T) {
+	expectations := map[string][]string{
+		`foo/bar`:              []string{`foo/bar`},
+		`src/**/*.{go,proto}`:  []string{`src/**/*.go`, `src/**/*.proto`},
+		`foo{,.bak}`:           []string{`foo`, `foo.bak`},
+		`a{b,c}d{e,f}`:         []string{`abde`, `abdf`, `acde`, `acdf`},
+		`{a,b{c,d{e,f}}}`:      []string{`a`, `bc`, `bde`, `bdf`},
+		`!{foo,bar}/*`:         []string{`!foo/*`, `!bar/*`},
+		`{\!foo,bar}`:          []string{`\!foo`, `bar`},
+		`{a\,b,c}`:             []string{`a\,b`, `c`},
+		`[{,}]{x,y}`:           []string{`[{,}]x`, `[{,}]y`},
+		`{foo,bar}/\{baz\}`:    []string{`foo/\{baz\}`, `bar/\{baz\}`},
+		`}{a,b},`:              []string{`\}a\,`, `\}b\,`},
+		`{[ab],?,*,**}/{x,}`:   []string{`[ab]/x`, `[ab]/`, `?/x`, `?/`, `*/x`, `*/`, `**/x`, `**/`},
+		`{svc:*}/{a,b}/{p:**}`: []string{`{svc:*}/a/{p:**}`, `{svc:*}/b/{p:**}`},
+	}
+
+	for pattern, expected := range expectations {
+		expanded, err := ExpandBraces(pattern, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, expanded, "Unexpected expansion of `%s`", pattern)
+	}
+
+	// With extended globs, a ! which opens a negated group is not a negation prefix
+	extGlobOptions := &Options{Separator: '/', MatchAtStart: true, MatchAtEnd: true, ExtGlob: true}
+	for pattern, expected := range map[string][]string{
+		`!(a|b){x,y}`: {`!(a|b)x`, `!(a|b)y`},
+		`!!(a){x,y}`:  {`!!(a)x`, `!!(a)y`},
+		`!{a,b}!(c)`:  {`!a!(c)`, `!b!(c)`},
+		`!!{(a),b}`:   {`!!\(a\)`, `!!b`},
+	} {
+		expanded, err := ExpandBraces(pattern, extGlobOptions)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, expanded, "Unexpected expansion of `%s`", pattern)
+	}
+
+	// Braces which don't form brace expressions are literal
+	for pattern, expected := range map[string][]string{
+		`{foo`:         {`{foo`},
+		`{a}/b`:        {`{a}/b`},
+		`foo/{a,{b,c}`: {`foo/\{a\,b`, `foo/\{a\,c`},
+		`{x,{}}`:       {`x`, `\{\}`},
+	} {
+		expanded, err := ExpandBraces(pattern, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, expanded, "Unexpected expansion of `%s`", pattern)
+	}
+
+	// As are brackets which aren't closed, unless parsing strictly
+	for pattern, expected := range map[string][]string{
+		`{[a}`:   {`{[a}`},
+		`{x,[a}`: {`x`, `\[a`},
+	} {
+		expanded, err := ExpandBraces(pattern, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, expanded, "Unexpected expansion of `%s`", pattern)
+		_, err = ExpandBraces(pattern, &Options{Separator: '/', Strict: true})
+		assert.Error(t, err, "Expanding `%s` strictly should fail", pattern)
+	}
+}
+
+// Expanded patterns, compiled separately, should match the same strings as the original pattern
+func TestExpandBraces_Equivalence(t *testing.T) {
+	inputs := []string{`foo/a.go`, `bar/baz/b.proto`, `bar/qux/c.go`, `bar/a.go`, `foo/a.c`, `foo/bar/a.go`, `a`, `a/`,
+		`a/b`, `a//b`, `a/x/b`, `a/p/q/b`, `a/xb`, `a/c/d`, `x`, `/x`, `bx`, `x/a`, `a/x`, `b`, `b/c`, `x/y`, `x/z`,
+		`x/y/z`, `x/y/p/z`, `x/p/y/z`, `x/p/q`, `main.go`, `cmd/main.go`, `cmd/x/main.go`}
+	for _, pattern := range []string{
+		`{foo,bar/{baz,qux}}/*.{go,proto}`,
+		// Globstars at the boundaries of brace alternatives
+		`a/{**,x}/b`,
+		`a/{b,**}`,
+		`**{a,}`,
+		`{a,}/**`,
+		`{a/,b}**`,
+		`**/{a,x}`,
+		`{**,b}/c`,
+		`x/{**,y}/{z,**}`,
+		`{**/,}*.go`,
+		// Stars glued into a globstar by an expansion
+		`*{,/}*`,
+		`a*{,/}*`,
+		`b{a,b}/*{,/}*`,
+		`*{,/}*/{1..2}`,
+	} {
+		glob, err := Compile(pattern, nil)
+		assert.NoError(t, err)
+		expanded, err := ExpandBraces(pattern, nil)
+		assert.NoError(t, err)
+		set, err := CompileGlobSet(expanded, nil)
+		assert.NoError(t, err)
+
+		for _, s := range inputs {
+			assert.Equal(t, set.MatchString(s), glob.MatchString(s), "Expansion of `%s` disagrees on `%s`", pattern, s)
+		}
+	}
+
+	patternParts := []string{`a`, `*`, `*`, `**`, `/`, `{,/}`, `{a,}`, `{,*}`, `{*,b}`, `{a*,/}`, `{,{,*}}`}
+	inputParts := []string{`a`, `b`, `aa`, `,`, `/`, `/`}
+	patterns := equivalence.Parts{Parts: patternParts, Min: 1, Max: 5}
+	equivalence.Check(patterns, equivalence.Parts{Parts: inputParts, Max: 6},
+		func(pattern string) func(string) {
+			glob, err := Compile(pattern, nil)
+			if !assert.NoError(t, err) {
+				return nil
+			}
+			expanded, err := ExpandBraces(pattern, nil)
+			assert.NoError(t, err)
+			set, err := CompileGlobSet(expanded, nil)
+			if err != nil {
+				// An expansion is empty
+				return nil
+			}
+			return func(s string) {
+				assert.Equal(t, set.MatchString(s), glob.MatchString(s), "Expansion of `%s` disagrees on `%s`", pattern,
+					s)
+			}
+		})
+}
+
+func TestExpandBraces_EquivalentSubmatches(t *testing.T) {
+	// A pattern compiled from its expansions still has a group for each of its own wildcards
+	glob, err := Compile(`a/{**,x}/{name:*}.{go,?s}`, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{`a/b.go`, ``, `b`, ``}, glob.FindStringSubmatch(`a/b.go`))
+	assert.Equal(t, []string{`a/p/q/b.js`, `p/q`, `b`, `j`}, glob.FindStringSubmatch(`a/p/q/b.js`))
+	assert.Equal(t, []int{0, 6, -1, -1, 2, 3, -1, -1}, glob.FindStringSubmatchIndex(`a/b.go`))
+	assert.Equal(t, map[string]string{`name`: `b`}, glob.Captures(`a/x/b.ts`))
+	assert.Nil(t, glob.FindStringSubmatch(`a/b.c`))
+
+	rewriter, err := NewRewriter(glob, `{1}:{name}`)
+	assert.NoError(t, err)
+	rewritten, ok := rewriter.Rewrite(`a/p/b.go`)
+	assert.True(t, ok)
+	assert.Equal(t, `p:b`, rewritten)
+}
+
+func TestExpandBraces_Sequences(t *testing.T) {
+	expectations := map[string][]string{
+		`{1..5}`:          []string{`1`, `2`, `3`, `4`, `5`},
+		`{5..1}`:          []string{`5`, `4`, `3`, `2`, `1`},
+		`{01..10..3}`:     []string{`01`, `04`, `07`, `10`},
+		`{0..100..25}`:    []string{`0`, `25`, `50`, `75`, `100`},
+		`{-2..2..-2}`:     []string{`-2`, `0`, `2`},
+		`{-05..05..5}`:    []string{`-05`, `000`, `005`},
+		`{a..e..2}`:       []string{`a`, `c`, `e`},
+		`{Z..X}`:          []string{`Z`, `Y`, `X`},
+		`x{1..2}{a..b}`:   []string{`x1a`, `x1b`, `x2a`, `x2b`},
+		`{1..2,3..4}`:     []string{`1..2`, `3..4`},
+		`{1\..2}`:         []string{`{1\..2}`},
+		`{1..}`:           []string{`{1..}`},
+		`file.{1..3}.bak`: []string{`file.1.bak`, `file.2.bak`, `file.3.bak`},
+	}
+
+	for pattern, expected := range expectations {
+		expanded, err := ExpandBraces(pattern, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, expanded, "Unexpected expansion of `%s`", pattern)
+	}
+
+	for _, pattern := range []string{`{1..100000}`, `{0..99999999999999999999}`, `{1..5..0}`} {
+		_, err := ExpandBraces(pattern, nil)
+		assert.Error(t, err, "Expanding `%s` should fail", pattern)
+	}
+}
+
+func TestExpandBraces_TooMany(t *testing.T) {
+	expanded, err := ExpandBraces(`{1..100}{1..100}`, nil)
+	assert.NoError(t, err)
+	assert.Len(t, expanded, 10000)
+
+	// The number of expansions is limited, even if each brace expression is within the limit
+	for pattern, offset := range map[string]int{
+		`!{1..9999}{1..9999}`:         10,
+		`{1..101}/{1..100}`:           9,
+		`x{{1..9999},{1..9999}}`:      1,
+		`{1..10}{{1..100},{1..1000}}`: 7,
+	} {
+		_, err := ExpandBraces(pattern, nil)
+		if patternErr, ok := err.(*PatternError); assert.True(t, ok, "Expanding `%s` should fail", pattern) {
+			assert.Equal(t, ErrTooManyExpansions, patternErr.Kind)
+			assert.Equal(t, offset, patternErr.Offset, "Unexpected offset for `%s`", pattern)
+		}
+	}
+}
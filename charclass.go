@@ -0,0 +1,277 @@
+package ohmyglob
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// posixClasses maps the names of POSIX character classes (as used in [[:alpha:]]) to the Unicode tables whose union
+// makes up the class. Like ?, classes match whole runes, so multi-byte characters are treated as members.
+var posixClasses = map[string][]*unicode.RangeTable{
+	"alnum":  {unicode.Letter, unicode.Nd},
+	"alpha":  {unicode.Letter},
+	"blank":  {unicode.Zs, asciiTable("\t")},
+	"cntrl":  {unicode.Cc},
+	"digit":  {unicode.Nd},
+	"graph":  {unicode.L, unicode.M, unicode.N, unicode.P, unicode.S},
+	"lower":  {unicode.Lower},
+	"print":  {unicode.L, unicode.M, unicode.N, unicode.P, unicode.S, unicode.Zs},
+	"punct":  {unicode.P, unicode.S},
+	"space":  {unicode.White_Space},
+	"upper":  {unicode.Upper},
+	"word":   {unicode.Letter, unicode.Nd, asciiTable("_")},
+	"xdigit": {asciiTable("0123456789ABCDEFabcdef")},
+}
+
+// Returns a table containing the (ASCII) runes of the passed string, which must be in order
+func asciiTable(runes string) *unicode.RangeTable {
+	table := &unicode.RangeTable{}
+	for _, r := range runes {
+		table.R16 = append(table.R16, unicode.Range16{Lo: uint16(r), Hi: uint16(r), Stride: 1})
+	}
+	return table
+}
+
+// posixClassRanges returns the ranges that make up the named POSIX character class, and whether the name was valid
+func posixClassRanges(name string) ([]runeRange, bool) {
+	tables, ok := posixClasses[name]
+	if !ok {
+		return nil, false
+	}
+
+	ranges := make([]runeRange, 0, 64)
+	for _, table := range tables {
+		for _, r16 := range table.R16 {
+			ranges = appendStridedRange(ranges, rune(r16.Lo), rune(r16.Hi), rune(r16.Stride))
+		}
+		for _, r32 := range table.R32 {
+			ranges = appendStridedRange(ranges, rune(r32.Lo), rune(r32.Hi), rune(r32.Stride))
+		}
+	}
+	return normaliseRanges(ranges), true
+}
+
+// Appends the runes from lo to hi (inclusive) at the given stride to ranges
+func appendStridedRange(ranges []runeRange, lo, hi, stride rune) []runeRange {
+	if stride == 1 {
+		return append(ranges, runeRange{lo, hi})
+	}
+	for r := lo; r <= hi; r += stride {
+		ranges = append(ranges, runeRange{r, r})
+	}
+	return ranges
+}
+
+// A contiguous, inclusive range of runes within a character class
+type runeRange struct {
+	lo, hi rune
+}
+
+// charClass is a parsed bracket expression, eg. [a-z] or [!abc]
+type charClass struct {
+	// Set to true if the class was negated ([!...] or [^...])
+	negated bool
+	// The sorted, non-overlapping ranges that are members of the class
+	ranges []runeRange
+}
+
+// classMember is a single member of a bracket expression: either a rune, or a named class (eg. [:alpha:])
+type classMember struct {
+	r       rune
+	escaped bool
+	named   []runeRange
+	// The byte offsets within the token of the start and end of the member
+	start, end int
+}
+
+// parseCharClass parses a bracket expression token (including the enclosing brackets), as yielded by the tokeniser.
+// The offset of the token within the pattern is used to report the position of any error, and the escaper is the
+// character which escapes the following member.
+func parseCharClass(token string, offset int, escaper rune) (*charClass, error) {
+	if !strings.HasPrefix(token, "[") || !strings.HasSuffix(token, "]") || len(token) < 3 {
+		return nil, newPatternError(ErrUnterminatedCharClass, offset, token, "invalid character class \"%s\"", token)
+	}
+
+	class := &charClass{}
+	i, end := 1, len(token)-1
+	if token[i] == '!' || token[i] == '^' {
+		class.negated = true
+		i++
+	}
+	if i == end {
+		return nil, newPatternError(ErrEmptyCharClass, offset, token, "empty character class \"%s\"", token)
+	}
+
+	// Read the members of the class one at a time, consuming any escapers
+	members := make([]classMember, 0, end-i)
+	for i < end {
+		if strings.HasPrefix(token[i:], "[:") {
+			if nameLen := strings.Index(token[i+2:end], ":]"); nameLen > 0 {
+				name := token[i+2 : i+2+nameLen]
+				ranges, ok := posixClassRanges(name)
+				if !ok {
+					return nil, newPatternError(ErrUnknownCharClass, offset+i, token[i:i+nameLen+4],
+						"unknown character class \"[:%s:]\"", name)
+				}
+				members = append(members, classMember{named: ranges, start: i, end: i + nameLen + 4})
+				i += nameLen + 4
+				continue
+			}
+		}
+
+		start := i
+		r, width := utf8.DecodeRuneInString(token[i:])
+		escaped := false
+		if r == escaper && i+width < end {
+			i += width
+			r, width = utf8.DecodeRuneInString(token[i:])
+			escaped = true
+		}
+		i += width
+		members = append(members, classMember{r: r, escaped: escaped, start: start, end: i})
+	}
+
+	for i := 0; i < len(members); i++ {
+		if members[i].named != nil {
+			class.ranges = append(class.ranges, members[i].named...)
+			continue
+		}
+
+		lo := members[i].r
+		// An unescaped - between two members denotes a range; at the start or end of the class it is a literal
+		if i+2 < len(members) && members[i+1].r == '-' && !members[i+1].escaped && members[i+1].named == nil &&
+			members[i+2].named == nil {
+			hi := members[i+2].r
+			if hi < lo {
+				start, end := members[i].start, members[i+2].end
+				return nil, newPatternError(ErrInvalidRange, offset+start, token[start:end],
+					"invalid range %s-%s in character class \"%s\"", string(lo), string(hi), token)
+			}
+			class.ranges = append(class.ranges, runeRange{lo, hi})
+			i += 2
+			continue
+		}
+		class.ranges = append(class.ranges, runeRange{lo, lo})
+	}
+
+	class.ranges = normaliseRanges(class.ranges)
+	return class, nil
+}
+
+// normaliseRanges sorts the passed ranges and merges any that overlap or are adjacent
+func normaliseRanges(ranges []runeRange) []runeRange {
+	if len(ranges) == 0 {
+		return ranges
+	}
+
+	sort.Slice(ranges, func(i, j int) bool {
+		return ranges[i].lo < ranges[j].lo
+	})
+	result := make([]runeRange, 0, len(ranges))
+	current := ranges[0]
+	for _, rr := range ranges[1:] {
+		if rr.lo <= current.hi+1 {
+			if rr.hi > current.hi {
+				current.hi = rr.hi
+			}
+			continue
+		}
+		result = append(result, current)
+		current = rr
+	}
+	return append(result, current)
+}
+
+// subtractRune returns the ranges with the passed rune removed
+func subtractRune(ranges []runeRange, r rune) []runeRange {
+	result := make([]runeRange, 0, len(ranges)+1)
+	for _, rr := range ranges {
+		if r < rr.lo || r > rr.hi {
+			result = append(result, rr)
+			continue
+		}
+		if rr.lo < r {
+			result = append(result, runeRange{rr.lo, r - 1})
+		}
+		if r < rr.hi {
+			result = append(result, runeRange{r + 1, rr.hi})
+		}
+	}
+	return result
+}
+
+// Writes a rune so that it is always interpreted literally within a regular expression character class
+func writeClassRune(buf *bytes.Buffer, r rune) {
+	if r < utf8.RuneSelf && (r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+		buf.WriteRune(r)
+		return
+	}
+	fmt.Fprintf(buf, `\x{%x}`, r)
+}
+
+// foldASCII adds the opposite-case counterparts of any ASCII letters to the class
+func (c *charClass) foldASCII() {
+	folded := make([]runeRange, 0, len(c.ranges)*2)
+	folded = append(folded, c.ranges...)
+	for _, rr := range c.ranges {
+		if lo, hi := maxRune(rr.lo, 'A'), minRune(rr.hi, 'Z'); lo <= hi {
+			folded = append(folded, runeRange{lo - 'A' + 'a', hi - 'A' + 'a'})
+		}
+		if lo, hi := maxRune(rr.lo, 'a'), minRune(rr.hi, 'z'); lo <= hi {
+			folded = append(folded, runeRange{lo - 'a' + 'A', hi - 'a' + 'A'})
+		}
+	}
+	c.ranges = normaliseRanges(folded)
+}
+
+func minRune(a, b rune) rune {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxRune(a, b rune) rune {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// regexString returns a regular expression component matching any single rune in the class. The excluded runes (the
+// separators, and any others which wildcards never match) are never matched, regardless of whether they were specified
+// as members of the class.
+func (c *charClass) regexString(excluded []rune) string {
+	ranges := c.ranges
+	if !c.negated {
+		for _, r := range excluded {
+			ranges = subtractRune(ranges, r)
+		}
+	}
+	if len(ranges) == 0 {
+		// Nothing can be matched
+		return `[^\x00-\x{10FFFF}]`
+	}
+
+	buf := new(bytes.Buffer)
+	buf.WriteRune('[')
+	if c.negated {
+		buf.WriteRune('^')
+		for _, r := range excluded {
+			writeClassRune(buf, r)
+		}
+	}
+	for _, rr := range ranges {
+		writeClassRune(buf, rr.lo)
+		if rr.hi != rr.lo {
+			buf.WriteRune('-')
+			writeClassRune(buf, rr.hi)
+		}
+	}
+	buf.WriteRune(']')
+	return buf.String()
+}
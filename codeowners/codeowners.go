@@ -0,0 +1,279 @@
+// Package codeowners resolves the owners of paths from a GitHub or GitLab CODEOWNERS file.
+//
+// Each line of the file is a pattern followed by its owners (eg. "/docs/ @org/docs-team docs@example.com"). Patterns
+// follow gitignore's rules, except that they can't be negated: a pattern without a slash (other than a trailing one)
+// matches at any depth, a leading slash anchors it to the root, and a pattern matching a directory also matches
+// everything beneath it, unless its last segment is a lone * (so "docs/*" matches "docs/index.md" but not
+// "docs/api/index.md"). The last pattern matching a path decides its owners; a pattern with no owners leaves the paths
+// it matches without any.
+//
+// GitLab's sections are also supported: a line such as "[Docs]" begins a section, "^[Docs]" begins one whose approval
+// is optional, "[Docs][2]" one requiring 2 approvals, and "[Docs] @docs-team" one whose rules default to the given
+// owners. The last matching pattern is found within each section, and their owners are combined.
+package codeowners
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	glob "github.com/obeattie/ohmyglob"
+	"github.com/obeattie/ohmyglob/gitignore"
+)
+
+// Section is a named group of rules, introduced by a header line
+type Section struct {
+	// The name of the section, as written in its first header but with runs of whitespace collapsed (sections are named
+	// case-insensitively, and those with the same name are combined)
+	Name string
+	// Set to true if approval from the section's owners is optional (ie. the header begins with ^)
+	Optional bool
+	// The number of approvals required from the section's owners (eg. 2 for "[Docs][2]"), or 0 if not given
+	Approvals int
+	// The owners of the rules in the section which don't list any
+	DefaultOwners []string
+	// The line of the file on which the section's first header appears, starting at 1
+	Line int
+}
+
+// Rule is a single pattern from a CODEOWNERS file, and its owners
+type Rule struct {
+	// The pattern, as written in the file
+	Pattern string
+	// The owners of the paths the pattern matches: those listed after the pattern or, if there are none, the default
+	// owners of its section
+	Owners []string
+	// The line of the file on which the pattern appears, starting at 1
+	Line int
+	// The section containing the rule, or nil if it appears before any section header
+	Section *Section
+	// The translated pattern, which matches the paths the rule applies to (without regard to their parent directories)
+	Glob glob.Glob
+}
+
+// Matcher resolves the owners of paths from the rules of a CODEOWNERS file. Paths are separated by slashes, and are
+// relative to the root of the repository. A Matcher is immutable.
+type Matcher interface {
+	// Owners returns the owners of the path: the combined owners of the rules returned by MatchingRules, without
+	// duplicates
+	Owners(path string) []string
+	// MatchingRules returns the rule which decides the owners of the path in each section that has one, in the order
+	// the sections first appear (the rules which appear before any section header come first). For a file without
+	// sections, this is at most one rule.
+	MatchingRules(path string) []*Rule
+	// Rules returns the rules of the file, in order
+	Rules() []*Rule
+	// Sections returns the sections of the file, in the order they first appear
+	Sections() []*Section
+}
+
+// sectionMatcher matches the rules within a section
+type sectionMatcher struct {
+	// Map the globs of the rules which can match a path itself, and of those which can match its parent directories,
+	// to their indices within the section
+	paths glob.GlobMap[int]
+	dirs  glob.GlobMap[int]
+	rules []*Rule
+}
+
+type matcher struct {
+	rules    []*Rule
+	sections []*Section
+	// The matcher of the rules before any section header, followed by that of each section
+	matchers []*sectionMatcher
+}
+
+// sectionHeader matches a section header, capturing the optional marker, the name, the number of approvals, and the
+// rest of the line
+var sectionHeader = regexp.MustCompile(`^(\^?)\[([^\]]+)\](?:\[(\d+)\])?(?:\s+(.*))?$`)
+
+// Parse reads the contents of a CODEOWNERS file, and constructs a Matcher from its rules
+func Parse(r io.Reader) (Matcher, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return Compile(lines)
+}
+
+// Compile constructs a Matcher from the lines of a CODEOWNERS file. Blank lines and comments (from a # at the start of
+// a line or of a field, to the end of the line) are skipped, as are lines with negated or invalid patterns, which
+// GitHub and GitLab also skip.
+func Compile(lines []string) (Matcher, error) {
+	m := &matcher{}
+	sectionsByName := make(map[string]int)
+	var current *Section
+	var builders [][]*Rule
+	builders = append(builders, nil)
+	builderIdx := 0
+	for i, line := range lines {
+		fields := splitFields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		if header := sectionHeader.FindStringSubmatch(strings.Join(fields, " ")); header != nil {
+			key := strings.ToLower(header[2])
+			idx, ok := sectionsByName[key]
+			if !ok {
+				idx = len(m.sections)
+				sectionsByName[key] = idx
+				m.sections = append(m.sections, &Section{Name: header[2], Line: i + 1})
+				builders = append(builders, nil)
+			}
+			current = m.sections[idx]
+			builderIdx = idx + 1
+			current.Optional = header[1] == "^"
+			if header[3] != "" {
+				current.Approvals, _ = strconv.Atoi(header[3])
+			}
+			if header[4] != "" {
+				current.DefaultOwners = strings.Fields(header[4])
+			}
+			continue
+		}
+
+		parsed := gitignore.ParseRule(fields[0])
+		if parsed == nil || parsed.Negated {
+			continue
+		}
+		rule := &Rule{
+			Pattern: fields[0],
+			Owners:  fields[1:],
+			Line:    i + 1,
+			Section: current,
+			Glob:    parsed.Glob,
+		}
+		m.rules = append(m.rules, rule)
+		builders[builderIdx] = append(builders[builderIdx], rule)
+	}
+
+	for _, rules := range builders {
+		section, err := newSectionMatcher(rules)
+		if err != nil {
+			return nil, err
+		}
+		m.matchers = append(m.matchers, section)
+	}
+	// Rules which list no owners take the defaults of their section (which may have been given by a later header)
+	for _, rule := range m.rules {
+		if len(rule.Owners) == 0 && rule.Section != nil {
+			rule.Owners = rule.Section.DefaultOwners
+		}
+	}
+	return m, nil
+}
+
+func newSectionMatcher(rules []*Rule) (*sectionMatcher, error) {
+	var pathGlobs, dirGlobs []glob.Glob
+	var pathIndices, dirIndices []int
+	for i, rule := range rules {
+		pattern := rule.Pattern
+		if !strings.HasSuffix(pattern, "/") {
+			pathGlobs, pathIndices = append(pathGlobs, rule.Glob), append(pathIndices, i)
+		}
+		if pattern != "*" && !strings.HasSuffix(pattern, "/*") {
+			dirGlobs, dirIndices = append(dirGlobs, rule.Glob), append(dirIndices, i)
+		}
+	}
+
+	var err error
+	s := &sectionMatcher{rules: rules}
+	if s.paths, err = glob.NewGlobMap(pathGlobs, pathIndices); err != nil {
+		return nil, err
+	}
+	if s.dirs, err = glob.NewGlobMap(dirGlobs, dirIndices); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Splits the line into whitespace-separated fields, up to any comment. Whitespace escaped with a backslash does not
+// separate fields (and the escape is kept, as it is meaningful in patterns).
+func splitFields(line string) []string {
+	var fields []string
+	field := new(strings.Builder)
+	flush := func() {
+		if field.Len() > 0 {
+			fields = append(fields, field.String())
+			field.Reset()
+		}
+	}
+
+	runes := []rune(line)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '\\' && i+1 < len(runes):
+			field.WriteRune(r)
+			field.WriteRune(runes[i+1])
+			i++
+		case unicode.IsSpace(r):
+			flush()
+		case r == '#' && field.Len() == 0:
+			flush()
+			return fields
+		default:
+			field.WriteRune(r)
+		}
+	}
+	flush()
+	return fields
+}
+
+// Returns the index of the rule which decides the owners of the path within the section, or -1 if there is none: the
+// last rule matching either the path itself, or one of its parent directories
+func (s *sectionMatcher) match(path string) int {
+	winner := -1
+	if idx, ok := s.paths.Get(path); ok {
+		winner = idx
+	}
+	for i := 0; i < len(path); i++ {
+		if path[i] == '/' {
+			if idx, ok := s.dirs.Get(path[:i]); ok && idx > winner {
+				winner = idx
+			}
+		}
+	}
+	return winner
+}
+
+func (m *matcher) MatchingRules(path string) []*Rule {
+	path = strings.Trim(path, "/")
+	var result []*Rule
+	for _, s := range m.matchers {
+		if idx := s.match(path); idx >= 0 {
+			result = append(result, s.rules[idx])
+		}
+	}
+	return result
+}
+
+func (m *matcher) Owners(path string) []string {
+	var owners []string
+	seen := make(map[string]bool)
+	for _, rule := range m.MatchingRules(path) {
+		for _, owner := range rule.Owners {
+			if !seen[owner] {
+				seen[owner] = true
+				owners = append(owners, owner)
+			}
+		}
+	}
+	return owners
+}
+
+func (m *matcher) Rules() []*Rule {
+	return append([]*Rule(nil), m.rules...)
+}
+
+func (m *matcher) Sections() []*Section {
+	return append([]*Section(nil), m.sections...)
+}
@@ -0,0 +1,190 @@
+package codeowners
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// The example file from GitHub's documentation
+const githubExample = `# This is a comment.
+# Each line is a file pattern followed by one or more owners.
+
+# These owners will be the default owners for everything in
+# the repo. Unless a later match takes precedence,
+# @global-owner1 and @global-owner2 will be requested for
+# review when someone opens a pull request.
+*       @global-owner1 @global-owner2
+
+# Order is important; the last matching pattern takes the most
+# precedence. When someone opens a pull request that only
+# modifies JS files, only @js-owner and not the global
+# owner(s) will be requested for a review.
+*.js    @js-owner #This is an inline comment.
+
+# You can also use email addresses if you prefer. They'll be
+# used to look up users just like we do for commit author
+# emails.
+*.go docs@example.com
+
+# Teams can be specified as code owners as well.
+*.txt @octo-org/octocats
+
+# In this example, @doctocat owns any files in the build/logs
+# directory at the root of the repository and any of its
+# subdirectories.
+/build/logs/ @doctocat
+
+# The ` + "`docs/*`" + ` pattern will match files like
+# ` + "`docs/getting-started.md`" + ` but not further nested files like
+# ` + "`docs/build-app/troubleshooting.md`" + `.
+docs/*  docs@example.com
+
+# In this example, @octocat owns any file in an apps directory
+# anywhere in your repository.
+apps/ @octocat
+
+# In this example, @doctocat owns any file in the ` + "`/docs`" + `
+# directory in the root of your repository and any of its
+# subdirectories.
+/docs/ @doctocat
+
+# In this example, any change inside the ` + "`/scripts`" + ` directory
+# will require approval from @doctocat or @octocat.
+/scripts/ @doctocat @octocat
+
+# In this example, @octocat owns any file in a ` + "`/logs`" + ` directory such as
+# ` + "`/build/logs`" + `, ` + "`/scripts/logs`" + `, and ` + "`/deeply/nested/logs`" + `. Any changes
+# in a ` + "`/logs`" + ` directory will require approval from @octocat.
+**/logs @octocat
+
+# In this example, @octocat owns any file in the ` + "`/apps`" + `
+# directory in the root of your repository except for the ` + "`/apps/github`" + `
+# subdirectory, as its owners are left empty.
+/apps/ @octocat
+/apps/github
+`
+
+func TestGitHubExample(t *testing.T) {
+	m, err := Parse(strings.NewReader(githubExample))
+	assert.NoError(t, err)
+	assert.Empty(t, m.Sections())
+
+	cases := []struct {
+		path   string
+		owners []string
+		line   int
+	}{
+		{`README.md`, []string{`@global-owner1`, `@global-owner2`}, 8},
+		{`src/app.js`, []string{`@js-owner`}, 14},
+		{`main.go`, []string{`docs@example.com`}, 19},
+		{`notes/todo.txt`, []string{`@octo-org/octocats`}, 22},
+		{`build/logs/out.log`, []string{`@octocat`}, 50},
+		{`build/logs/2024/out.md`, []string{`@octocat`}, 50},
+		{`build/other/out.md`, []string{`@global-owner1`, `@global-owner2`}, 8},
+		{`docs/getting-started.md`, []string{`@doctocat`}, 41},
+		{`docs/getting-started.txt`, []string{`@doctocat`}, 41},
+		// docs/* is anchored to the root, as it contains a slash
+		{`sub/docs/getting-started.md`, []string{`@global-owner1`, `@global-owner2`}, 8},
+		{`docs/build-app/troubleshooting.md`, []string{`@doctocat`}, 41},
+		{`lib/apps/main.c`, []string{`@octocat`}, 36},
+		{`scripts/deploy.sh`, []string{`@doctocat`, `@octocat`}, 45},
+		{`deeply/nested/logs/x.md`, []string{`@octocat`}, 50},
+		{`apps/web/main.c`, []string{`@octocat`}, 55},
+		{`apps/github/main.c`, nil, 56},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.owners, m.Owners(c.path), "Unexpected owners for `%s`", c.path)
+		rules := m.MatchingRules(c.path)
+		if assert.Len(t, rules, 1, "One rule should match `%s`", c.path) {
+			assert.Equal(t, c.line, rules[0].Line, "Unexpected line for `%s`", c.path)
+		}
+	}
+}
+
+// An example file using GitLab's sections
+const gitlabExample = `* @everyone
+
+[Documentation] @docs-team
+docs/
+README.md @docs-lead
+
+^[Database][2] @database-team
+model/db/
+config/db/database-setup.md @docs-team
+
+[Paths With  Spaces]
+path\ with\ spaces/ @spaces-owner
+
+[documentation]
+*.md @markdown-owner
+`
+
+func TestGitLabSections(t *testing.T) {
+	m, err := Parse(strings.NewReader(gitlabExample))
+	assert.NoError(t, err)
+
+	sections := m.Sections()
+	if assert.Len(t, sections, 3) {
+		assert.Equal(t, &Section{Name: `Documentation`, DefaultOwners: []string{`@docs-team`}, Line: 3}, sections[0])
+		assert.Equal(t, &Section{Name: `Database`, Optional: true, Approvals: 2, DefaultOwners: []string{`@database-team`},
+			Line: 7}, sections[1])
+		assert.Equal(t, `Paths With Spaces`, sections[2].Name)
+	}
+
+	rules := m.Rules()
+	if assert.Len(t, rules, 7) {
+		assert.Nil(t, rules[0].Section)
+		assert.Equal(t, []string{`@docs-team`}, rules[1].Owners)
+		assert.Equal(t, sections[0], rules[1].Section)
+		assert.Equal(t, []string{`@docs-lead`}, rules[2].Owners)
+		assert.Equal(t, []string{`@database-team`}, rules[3].Owners)
+		assert.Equal(t, sections[0], rules[6].Section)
+	}
+
+	cases := map[string][]string{
+		`main.go`:                     {`@everyone`},
+		`docs/index.html`:             {`@everyone`, `@docs-team`},
+		`docs/index.md`:               {`@everyone`, `@markdown-owner`},
+		`README.md`:                   {`@everyone`, `@markdown-owner`},
+		`model/db/schema.rb`:          {`@everyone`, `@database-team`},
+		`config/db/database-setup.md`: {`@everyone`, `@markdown-owner`, `@docs-team`},
+		`path with spaces/file`:       {`@everyone`, `@spaces-owner`},
+	}
+	for path, owners := range cases {
+		assert.Equal(t, owners, m.Owners(path), "Unexpected owners for `%s`", path)
+	}
+
+	matching := m.MatchingRules(`config/db/database-setup.md`)
+	if assert.Len(t, matching, 3) {
+		assert.Equal(t, 1, matching[0].Line)
+		assert.Equal(t, 15, matching[1].Line)
+		assert.Equal(t, 9, matching[2].Line)
+	}
+}
+
+func TestCompile(t *testing.T) {
+	m, err := Compile([]string{
+		`*.c @c-owner # a comment`,
+		`!*.h @nobody`,
+		`\#notes.txt @notes-owner`,
+		`[abc].txt @class-owner`,
+		`   `,
+		`src/**/*.c @src-owner`,
+	})
+	assert.NoError(t, err)
+
+	rules := m.Rules()
+	if assert.Len(t, rules, 4) {
+		assert.Equal(t, []string{`@c-owner`}, rules[0].Owners)
+		assert.Equal(t, `\#notes.txt`, rules[1].Pattern)
+		assert.Equal(t, 3, rules[1].Line)
+	}
+	assert.Equal(t, []string{`@c-owner`}, m.Owners(`lib/x.c`))
+	assert.Nil(t, m.Owners(`lib/x.h`))
+	assert.Equal(t, []string{`@notes-owner`}, m.Owners(`#notes.txt`))
+	assert.Equal(t, []string{`@class-owner`}, m.Owners(`b.txt`))
+	assert.Equal(t, []string{`@src-owner`}, m.Owners(`src/a/b/x.c`))
+	assert.Equal(t, []string{`@src-owner`}, m.Owners(`/src/x.c`))
+}
@@ -0,0 +1,301 @@
+// Package dockerignore matches paths against the patterns of a .dockerignore file, reproducing the filtering Docker
+// applies to a build context.
+//
+// Unlike gitignore, every pattern is relative to the root of the context (whether or not it begins with a slash), a
+// pattern also matches everything beneath the directories it matches, and an exception (a pattern beginning with !)
+// can re-include a path even if one of its parent directories is excluded. Later patterns take precedence over earlier
+// ones. Each pattern is translated to an ohmyglob pattern which matches exactly the (clean) paths that Docker's
+// regular expression for it would; the one exception is that bracket expressions never match a slash.
+package dockerignore
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	glob "github.com/obeattie/ohmyglob"
+)
+
+// Rule is a single pattern from a .dockerignore file
+type Rule struct {
+	// The pattern, cleaned as Docker cleans it (eg. "/foo/./bar/" becomes "foo/bar"), and without any ! prefix
+	Pattern string
+	// The line of the file on which the pattern appears, starting at 1 (or, for patterns passed to Compile, the index
+	// of the pattern plus 1)
+	Line int
+	// Set to true if the pattern is an exception, which re-includes the paths it matches (ie. it begins with !)
+	Negated bool
+	// The translated pattern, which matches the paths the rule applies to (without regard to their parent directories)
+	Glob glob.Glob
+}
+
+// Matcher decides whether paths are excluded from a build context by the rules of a .dockerignore file. Paths are
+// separated by slashes, and are relative to the root of the context. A Matcher is immutable.
+type Matcher interface {
+	// Match reports whether the path is excluded: whether the last rule matching the path, or any of its parent
+	// directories, is not an exception
+	Match(path string) bool
+	// MatchingRule returns the rule which decides whether the path is excluded (the path is excluded if the rule is not
+	// Negated), or nil if no rule applies to it
+	MatchingRule(path string) *Rule
+	// Rules returns the rules of the file, in order
+	Rules() []*Rule
+}
+
+type matcher struct {
+	rules []*Rule
+	// Maps the globs of the rules to their indices
+	indices glob.GlobMap[int]
+}
+
+// ErrIllegalExclusion is returned (wrapped) by Compile and Parse for a pattern consisting of a lone "!"
+var ErrIllegalExclusion = errors.New("illegal exclusion pattern: \"!\"")
+
+var utf8BOM = []byte{0xef, 0xbb, 0xbf}
+
+// Parse reads the contents of a .dockerignore file, and constructs a Matcher from its rules. As in Docker, lines
+// beginning with # are comments, surrounding whitespace is removed from each line, blank lines are skipped, and each
+// pattern is cleaned (so a leading slash makes no difference).
+func Parse(r io.Reader) (Matcher, error) {
+	var patterns []string
+	var lines []int
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Bytes()
+		if line == 1 {
+			text = bytes.TrimPrefix(text, utf8BOM)
+		}
+		pattern := string(text)
+		// Comments are recognised before surrounding whitespace is removed
+		if strings.HasPrefix(pattern, "#") {
+			continue
+		}
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+
+		negated := pattern[0] == '!'
+		if negated {
+			pattern = strings.TrimSpace(pattern[1:])
+		}
+		if pattern != "" {
+			pattern = path.Clean(pattern)
+			if len(pattern) > 1 && pattern[0] == '/' {
+				pattern = pattern[1:]
+			}
+		}
+		if negated {
+			pattern = "!" + pattern
+		}
+		patterns = append(patterns, pattern)
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return compile(patterns, lines)
+}
+
+// Compile constructs a Matcher from a slice of patterns, as Docker does from the patterns of a .dockerignore file (or
+// those passed to it by other means). Surrounding whitespace is removed from each pattern, and empty patterns are
+// skipped. An error is returned for a lone "!" (wrapping ErrIllegalExclusion), or for a pattern which is malformed (eg.
+// one with an unterminated bracket expression, wrapping path.ErrBadPattern).
+func Compile(patterns []string) (Matcher, error) {
+	lines := make([]int, len(patterns))
+	for i := range lines {
+		lines[i] = i + 1
+	}
+	return compile(patterns, lines)
+}
+
+func compile(patterns []string, lines []int) (*matcher, error) {
+	m := &matcher{}
+	var globs []glob.Glob
+	var indices []int
+	for i, pattern := range patterns {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		pattern = path.Clean(pattern)
+
+		rule := &Rule{Line: lines[i]}
+		if pattern[0] == '!' {
+			if len(pattern) == 1 {
+				return nil, fmt.Errorf("line %d: %w", rule.Line, ErrIllegalExclusion)
+			}
+			rule.Negated = true
+			pattern = pattern[1:]
+		}
+		if _, err := path.Match(pattern, "."); err != nil {
+			return nil, fmt.Errorf("line %d: invalid pattern \"%s\": %w", rule.Line, pattern, err)
+		}
+		rule.Pattern = pattern
+
+		g, err := glob.Compile(translate(pattern), glob.DefaultOptions)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", rule.Line, err)
+		}
+		rule.Glob = g
+		indices = append(indices, len(m.rules))
+		globs = append(globs, g)
+		m.rules = append(m.rules, rule)
+	}
+
+	var err error
+	if m.indices, err = glob.NewGlobMap(globs, indices); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// translate converts a (clean) Docker pattern to the equivalent ohmyglob pattern. Docker converts a pattern to a
+// regular expression, except for those which it can match more simply (as a literal, a prefix or a suffix), and it is
+// this conversion that is mirrored here:
+//
+//   - * and ? match any text, or any single character, except a slash
+//   - ** matches any text (including slashes) which is empty or ends with a slash; a slash following it is consumed,
+//     so "a/**/b" matches "a/b" and "a/x/y/b", and "a**b" matches "ab" and "ax/b" (but not "axb")
+//   - A trailing ** matches any text, so "abc/**" matches everything beneath "abc"; for a pattern which is otherwise
+//     literal, a leading ** only requires the path to end with the rest of the pattern (so "**.go" matches "a.go")
+//   - \ escapes the next character, and everything else is literal
+func translate(pattern string) string {
+	runes := []rune(pattern)
+	buf := new(strings.Builder)
+	// Set to true until a wildcard is found; Docker matches patterns without any wildcards (other than a leading or
+	// trailing **) by string comparison
+	exact := true
+	leading, leadingSlash := false, false
+	for i, pos := 0, 0; pos < len(runes); i++ {
+		r := runes[pos]
+		pos++
+		switch {
+		case r == '*' && pos < len(runes) && runes[pos] == '*':
+			pos++
+			slash := pos < len(runes) && runes[pos] == '/'
+			if slash {
+				pos++
+			}
+			precededBySlash := strings.HasSuffix(buf.String(), "/")
+			switch {
+			case i == 0 && pos == len(runes):
+				return "**"
+			case i == 0:
+				// How the leading ** is written depends on whether the rest of the pattern is exact
+				leading, leadingSlash = true, slash
+			case pos == len(runes) && precededBySlash:
+				buf.WriteString("**/*")
+				exact = false
+			case pos == len(runes):
+				buf.WriteString("*/**")
+				exact = false
+			case leading && buf.Len() == 0:
+				// Redundant, following a leading **
+				exact = false
+			case precededBySlash:
+				buf.WriteString("**/")
+				exact = false
+			default:
+				// Empty, or any text ending with a slash (including a lone slash)
+				buf.WriteString("{,*/**/}")
+				exact = false
+			}
+		case r == '*', r == '?':
+			buf.WriteRune(r)
+			exact = false
+		case r == '\\':
+			if pos < len(runes) {
+				writeLiteral(buf, runes[pos])
+				pos++
+				exact = false
+			} else {
+				writeLiteral(buf, r)
+			}
+		case r == '[':
+			pos = writeBracket(buf, runes, pos)
+			exact = false
+		case r == ']':
+			writeLiteral(buf, r)
+			exact = false
+		default:
+			writeLiteral(buf, r)
+		}
+	}
+
+	if leading {
+		if exact && !leadingSlash {
+			return "**/*" + buf.String()
+		}
+		return "**/" + buf.String()
+	}
+	return buf.String()
+}
+
+// Writes a rune which is to be matched literally
+func writeLiteral(buf *strings.Builder, r rune) {
+	if strings.ContainsRune(`\*?[]{},!`, r) {
+		buf.WriteRune(glob.Escaper)
+	}
+	buf.WriteRune(r)
+}
+
+// Writes the bracket expression whose contents start at pos (following the opening [), returning the position
+// following it. The pattern is known to be well-formed. Docker passes bracket expressions through to a regular
+// expression, in which ^ negates the expression but ! does not.
+func writeBracket(buf *strings.Builder, runes []rune, pos int) int {
+	buf.WriteRune('[')
+	if pos < len(runes) && runes[pos] == '^' {
+		buf.WriteRune('^')
+		pos++
+	}
+	for first := true; pos < len(runes); first = false {
+		r := runes[pos]
+		pos++
+		switch {
+		case r == ']' && !first:
+			buf.WriteRune(r)
+			return pos
+		case r == '\\' && pos < len(runes):
+			buf.WriteRune(r)
+			buf.WriteRune(runes[pos])
+			pos++
+		case r == '!' || r == '*' || r == '?':
+			buf.WriteRune(glob.Escaper)
+			buf.WriteRune(r)
+		default:
+			buf.WriteRune(r)
+		}
+	}
+	return pos
+}
+
+func (m *matcher) MatchingRule(p string) *Rule {
+	p = path.Clean(p)
+	// The rule which takes precedence is the last to match the path or any of its parent directories
+	winner := -1
+	for i := 0; i <= len(p); i++ {
+		if i == len(p) || p[i] == '/' {
+			if idx, ok := m.indices.Get(p[:i]); ok && idx > winner {
+				winner = idx
+			}
+		}
+	}
+	if winner < 0 {
+		return nil
+	}
+	return m.rules[winner]
+}
+
+func (m *matcher) Match(p string) bool {
+	rule := m.MatchingRule(p)
+	return rule != nil && !rule.Negated
+}
+
+func (m *matcher) Rules() []*Rule {
+	return append([]*Rule(nil), m.rules...)
+}
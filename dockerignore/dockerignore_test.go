@@ -0,0 +1,294 @@
+package dockerignore
+
+import (
+	"errors"
+	"path"
+	"regexp"
+	"strings"
+	"testing"
+	"text/scanner"
+
+	"github.com/obeattie/ohmyglob/internal/equivalence"
+	"github.com/stretchr/testify/assert"
+)
+
+// Examples from moby's pattern matching tests, of whether a single pattern matches a path
+var patternCases = []struct {
+	pattern string
+	path    string
+	matches bool
+}{
+	{`fileutils.go`, `fileutils.go`, true},
+	{`*.go`, `fileutils.go`, true},
+	{`*.go`, `pkg/fileutils.go`, false},
+	{`**`, `file`, true},
+	{`**`, `file/`, true},
+	{`**/`, `file`, true},
+	{`**/`, `file/`, true},
+	{`**/**`, `dir/file`, true},
+	{`dir/**`, `dir/file`, true},
+	{`dir/**`, `dir/dir2/file`, true},
+	{`**/file`, `file`, true},
+	{`**/file`, `dir/file`, true},
+	{`**/file`, `dir/dir/file`, true},
+	{`**/file*`, `dir/dir/file`, true},
+	{`**/file*`, `dir/dir/file.txt`, true},
+	{`**/file*txt`, `dir/dir/file.txt`, true},
+	{`**/file*.txt`, `dir/dir/file.txt`, true},
+	{`**/file*.txt*`, `dir/dir/file.txt`, true},
+	{`**/**/*.txt`, `dir/dir/file.txt`, true},
+	{`**/**/*.txt2`, `dir/dir/file.txt`, false},
+	{`**/*.txt`, `file.txt`, true},
+	{`**/**/*.txt`, `file.txt`, true},
+	{`a**/*.txt`, `a/file.txt`, true},
+	{`a**/*.txt`, `a/dir/file.txt`, true},
+	{`a**/*.txt`, `a/dir/dir/file.txt`, true},
+	{`a/*.txt`, `a/dir/file.txt`, false},
+	{`a/*.txt`, `a/file.txt`, true},
+	{`a/*.txt**`, `a/file.txt`, true},
+	{`a[b-d]e`, `ae`, false},
+	{`a[b-d]e`, `ace`, true},
+	{`a[b-d]e`, `aae`, false},
+	{`a[^b-d]e`, `aze`, true},
+	{`.*`, `.foo`, true},
+	{`.*`, `foo`, false},
+	{`abc.def`, `abcdef`, false},
+	{`abc.def`, `abc.def`, true},
+	{`abc.def`, `abcZdef`, false},
+	{`abc?def`, `abcZdef`, true},
+	{`abc?def`, `abcdef`, false},
+	{`a\\`, `a\`, true},
+	{`**/foo/bar`, `foo/bar`, true},
+	{`**/foo/bar`, `dir/foo/bar`, true},
+	{`**/foo/bar`, `dir/dir2/foo/bar`, true},
+	{`abc/**`, `abc`, false},
+	{`abc/**`, `abc/def`, true},
+	{`abc/**`, `abc/def/ghi`, true},
+	{`**/.foo`, `.foo`, true},
+	{`**/.foo`, `bar.foo`, false},
+	{`a(b)c/def`, `a(b)c/def`, true},
+	{`a(b)c/def`, `a(b)c/xyz`, false},
+	{`a.|)$(}+{bc`, `a.|)$(}+{bc`, true},
+	{`dist/proxy.py-2.4.0rc3.dev36+g08acad9-py3-none-any.whl`, `dist/proxy.py-2.4.0rc3.dev36+g08acad9-py3-none-any.whl`, true},
+	{`dist/*.whl`, `dist/proxy.py-2.4.0rc3.dev36+g08acad9-py3-none-any.whl`, true},
+	// A leading ** before an otherwise literal pattern only requires the path to end with the rest of the pattern
+	{`**.go`, `a.go`, true},
+	{`**.go`, `dir/a.go`, true},
+	{`**o/a.go`, `foo/a.go`, true},
+	// A ** elsewhere matches text which is empty or ends with a slash
+	{`a**b`, `ab`, true},
+	{`a**b`, `a/b`, true},
+	{`a**b`, `ax/b`, true},
+	{`a**b`, `axb`, false},
+	{`a**`, `abc/def`, true},
+	// ! is not special within a bracket expression
+	{`a[!b]c`, `a!c`, true},
+	{`a[!b]c`, `abc`, true},
+	{`a[!b]c`, `axc`, false},
+}
+
+func TestTranslate(t *testing.T) {
+	for _, c := range patternCases {
+		m, err := Compile([]string{c.pattern})
+		if !assert.NoError(t, err, "`%s` should compile", c.pattern) {
+			continue
+		}
+		g := m.Rules()[0].Glob
+		assert.Equal(t, c.matches, g.MatchString(strings.TrimSuffix(c.path, "/")),
+			"`%s` (translated to `%s`) should match `%s`: %v", c.pattern, g.String(), c.path, c.matches)
+	}
+}
+
+// mobyMatch is a port of the way moby's patternmatcher package matches a single (clean) pattern against a path, which
+// translated patterns are checked against (with the documented exception of bracket expressions matching slashes)
+func mobyMatch(pattern, p string) bool {
+	const (
+		exactMatch = iota
+		prefixMatch
+		suffixMatch
+		regexpMatch
+	)
+
+	regStr := "^"
+	var scan scanner.Scanner
+	scan.Init(strings.NewReader(pattern))
+	matchType := exactMatch
+	for i := 0; scan.Peek() != scanner.EOF; i++ {
+		ch := scan.Next()
+		if ch == '*' {
+			if scan.Peek() == '*' {
+				scan.Next()
+				if scan.Peek() == '/' {
+					scan.Next()
+				}
+				if scan.Peek() == scanner.EOF {
+					if matchType == exactMatch {
+						matchType = prefixMatch
+					} else {
+						regStr += ".*"
+						matchType = regexpMatch
+					}
+				} else {
+					regStr += "(.*/)?"
+					matchType = regexpMatch
+				}
+				if i == 0 {
+					matchType = suffixMatch
+				}
+			} else {
+				regStr += "[^/]*"
+				matchType = regexpMatch
+			}
+		} else if ch == '?' {
+			regStr += "[^/]"
+			matchType = regexpMatch
+		} else if strings.ContainsRune(".+()|{}$", ch) {
+			regStr += `\` + string(ch)
+		} else if ch == '\\' {
+			if scan.Peek() != scanner.EOF {
+				regStr += `\` + string(scan.Next())
+				matchType = regexpMatch
+			} else {
+				regStr += `\`
+			}
+		} else if ch == '[' || ch == ']' {
+			regStr += string(ch)
+			matchType = regexpMatch
+			if ch == '[' && scan.Peek() == '^' {
+				// Unlike those in Docker's regular expressions, bracket expressions never match a slash
+				regStr += string(scan.Next()) + "/"
+			}
+		} else {
+			regStr += string(ch)
+		}
+	}
+
+	switch matchType {
+	case exactMatch:
+		return p == pattern
+	case prefixMatch:
+		return strings.HasPrefix(p, pattern[:len(pattern)-2])
+	case suffixMatch:
+		suffix := pattern[2:]
+		if strings.HasSuffix(p, suffix) {
+			return true
+		}
+		return suffix[0] == '/' && p == suffix[1:]
+	}
+	return regexp.MustCompile(regStr + "$").MatchString(p)
+}
+
+// Compares translated patterns with moby's matching, for random patterns and paths
+func TestTranslate_Equivalence(t *testing.T) {
+	patternParts := []string{`a`, `b`, `ab`, `.`, `*`, `?`, `/`, `/`, `**`, `[ab]`, `[^a]`, `\*`, `ä`, `{`, `,`}
+	pathParts := []string{`a`, `b`, `ab`, `ba`, `a.b`, `*`, `ä`, `{,`}
+	equivalence.Check(equivalence.Parts{Parts: patternParts, Min: 1, Max: 6},
+		equivalence.Parts{Parts: pathParts, Min: 1, Max: 4, Separator: `/`},
+		func(pattern string) func(string) {
+			pattern = path.Clean(pattern)
+			if _, err := path.Match(pattern, "."); err != nil {
+				return nil
+			}
+			m, err := Compile([]string{pattern})
+			if !assert.NoError(t, err) {
+				return nil
+			}
+			g := m.Rules()[0].Glob
+
+			return func(p string) {
+				expected := mobyMatch(pattern, p)
+				assert.Equal(t, expected, g.MatchString(p), "`%s` (translated to `%s`) should match `%s`: %v", pattern,
+					g.String(), p, expected)
+			}
+		})
+}
+
+func TestMatcher(t *testing.T) {
+	cases := []struct {
+		patterns []string
+		path     string
+		excluded bool
+	}{
+		// Examples from Docker's documentation
+		{[]string{`*/temp*`}, `somedir/temporary.txt`, true},
+		{[]string{`*/temp*`}, `somedir/temp`, true},
+		{[]string{`*/temp*`}, `temp`, false},
+		{[]string{`*/temp*`}, `somedir/subdir/temporary.txt`, false},
+		{[]string{`*/*/temp*`}, `somedir/subdir/temporary.txt`, true},
+		{[]string{`temp?`}, `tempa`, true},
+		{[]string{`temp?`}, `tempb`, true},
+		{[]string{`temp?`}, `somedir/tempa`, false},
+		{[]string{`**/*.go`}, `main.go`, true},
+		{[]string{`**/*.go`}, `cmd/app/main.go`, true},
+		{[]string{`*.md`, `!README.md`}, `CHANGELOG.md`, true},
+		{[]string{`*.md`, `!README.md`}, `README.md`, false},
+		{[]string{`*.md`, `!README*.md`, `README-secret.md`}, `README-secret.md`, true},
+		{[]string{`*.md`, `!README*.md`, `README-secret.md`}, `README-public.md`, false},
+		{[]string{`*.md`, `README-secret.md`, `!README*.md`}, `README-secret.md`, false},
+		// Patterns are always relative to the root, and are cleaned (so leading and trailing slashes make no difference)
+		{[]string{`/foo/bar/`}, `foo/bar`, true},
+		{[]string{`foo/bar`}, `foo/bar`, true},
+		{[]string{`foo/./bar/../baz`}, `foo/baz`, true},
+		{[]string{`bar`}, `foo/bar`, false},
+		// Patterns match everything beneath the directories they match
+		{[]string{`docs`}, `docs/guide/index.md`, true},
+		{[]string{`do*`}, `docs/guide/index.md`, true},
+		{[]string{`*`}, `docs/guide/index.md`, true},
+		// Exceptions can re-include paths within excluded directories
+		{[]string{`docs`, `!docs/README.md`}, `docs/README.md`, false},
+		{[]string{`docs`, `!docs/README.md`}, `docs/guide.md`, true},
+		{[]string{`*`, `!src`}, `src/main.go`, false},
+		{[]string{`*`, `!src`, `src/*.tmp`}, `src/x.tmp`, true},
+		{[]string{`*`, `!src`}, `docs/README.md`, true},
+		// But an exception is undone by a later pattern which matches a parent directory
+		{[]string{`!docs/README.md`, `docs`}, `docs/README.md`, true},
+		// Paths are cleaned
+		{[]string{`foo/bar`}, `./foo//bar/`, true},
+		{[]string{}, `foo`, false},
+	}
+	for _, c := range cases {
+		m, err := Parse(strings.NewReader(strings.Join(c.patterns, "\n")))
+		if !assert.NoError(t, err) {
+			continue
+		}
+		assert.Equal(t, c.excluded, m.Match(c.path), "Unexpected result for `%s` with %q", c.path, c.patterns)
+	}
+}
+
+func TestParse(t *testing.T) {
+	m, err := Parse(strings.NewReader("\xef\xbb\xbf# comment\n  # not a comment\n\n /build/  \n! build/keep \n**/*.o\n"))
+	assert.NoError(t, err)
+
+	rules := m.Rules()
+	if assert.Len(t, rules, 4) {
+		assert.Equal(t, `# not a comment`, rules[0].Pattern)
+		assert.Equal(t, 2, rules[0].Line)
+		assert.Equal(t, `build`, rules[1].Pattern)
+		assert.Equal(t, 4, rules[1].Line)
+		assert.False(t, rules[1].Negated)
+		assert.Equal(t, `build/keep`, rules[2].Pattern)
+		assert.Equal(t, 5, rules[2].Line)
+		assert.True(t, rules[2].Negated)
+		assert.Equal(t, `**/*.o`, rules[3].Pattern)
+		assert.Equal(t, 6, rules[3].Line)
+
+		assert.Equal(t, rules[1], m.MatchingRule(`build/out/x`))
+		assert.Equal(t, rules[2], m.MatchingRule(`build/keep/x`))
+		assert.Equal(t, rules[3], m.MatchingRule(`build/keep/x.o`))
+		assert.Equal(t, rules[0], m.MatchingRule(`# not a comment`))
+		assert.Nil(t, m.MatchingRule(`src/main.c`))
+	}
+}
+
+func TestErrors(t *testing.T) {
+	_, err := Compile([]string{`*.go`, `!`})
+	assert.True(t, errors.Is(err, ErrIllegalExclusion))
+	assert.EqualError(t, err, `line 2: illegal exclusion pattern: "!"`)
+
+	_, err = Parse(strings.NewReader("# comment\n[abc\n"))
+	assert.True(t, errors.Is(err, path.ErrBadPattern))
+	assert.EqualError(t, err, `line 2: invalid pattern "[abc": syntax error in pattern`)
+
+	_, err = Compile([]string{`foo\`})
+	assert.True(t, errors.Is(err, path.ErrBadPattern))
+}
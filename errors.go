@@ -0,0 +1,169 @@
+package ohmyglob
+
+import (
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+// PatternErrorKind identifies the kind of problem that was found in a pattern
+type PatternErrorKind uint8
+
+const (
+	// The pattern was empty (or consisted only of negation prefixes)
+	ErrEmptyPattern = PatternErrorKind(0x1)
+	// The separator in the Options is a character with special meaning in patterns
+	ErrInvalidSeparator = PatternErrorKind(0x2)
+	// A bracket expression was not closed (only reported when parsing strictly; otherwise, the opening bracket is
+	// literal)
+	ErrUnterminatedCharClass = PatternErrorKind(0x3)
+	// A bracket expression had no members
+	ErrEmptyCharClass = PatternErrorKind(0x4)
+	// A bracket expression contained an unknown named class (eg. [[:nope:]])
+	ErrUnknownCharClass = PatternErrorKind(0x5)
+	// A range within a bracket expression ended before it started (eg. [z-a])
+	ErrInvalidRange = PatternErrorKind(0x6)
+	// A brace was not closed (only reported when parsing strictly; otherwise, it is literal)
+	ErrUnterminatedBrace = PatternErrorKind(0x7)
+	// An extended glob group was not closed
+	ErrUnterminatedExtGlob = PatternErrorKind(0x8)
+	// A brace expression or extended glob group was closed while a group of the other kind nested within it was open
+	ErrMismatchedGroup = PatternErrorKind(0x9)
+	// A negated extended glob group was nested within another group
+	ErrNestedNegatedGroup = PatternErrorKind(0xa)
+	// A sequence expression had an invalid step, or endpoints that are out of range
+	ErrInvalidSequence = PatternErrorKind(0xb)
+	// A sequence expression produced more than MaxBraceSequenceLength values
+	ErrSequenceTooLong = PatternErrorKind(0xc)
+	// The regular expression the pattern was converted to could not be compiled (eg. because it was too large)
+	ErrRegexp = PatternErrorKind(0xd)
+	// The pattern had leading or trailing whitespace (only reported when parsing strictly)
+	ErrSurroundingWhitespace = PatternErrorKind(0xe)
+	// The pattern ended with an Escaper (only reported when parsing strictly)
+	ErrDanglingEscaper = PatternErrorKind(0xf)
+	// The pattern contained a run of three or more stars, or a globstar which is not a whole path segment (only
+	// reported when parsing strictly)
+	ErrAmbiguousStar = PatternErrorKind(0x10)
+	// A named capture had an invalid name (names must consist of ASCII letters, digits and underscores, and must not
+	// begin with a digit)
+	ErrInvalidCaptureName = PatternErrorKind(0x11)
+	// The same name was given to more than one named capture
+	ErrDuplicateCaptureName = PatternErrorKind(0x12)
+	// A rewrite template referred to a capture which is not in the pattern
+	ErrUnknownCapture = PatternErrorKind(0x13)
+	// The brace expressions in a pattern produced more than MaxBraceExpansions expansions
+	ErrTooManyExpansions = PatternErrorKind(0x14)
+)
+
+var patternErrorKindNames = map[PatternErrorKind]string{
+	ErrEmptyPattern:          "empty pattern",
+	ErrInvalidSeparator:      "invalid separator",
+	ErrUnterminatedCharClass: "unterminated character class",
+	ErrEmptyCharClass:        "empty character class",
+	ErrUnknownCharClass:      "unknown character class",
+	ErrInvalidRange:          "invalid range",
+	ErrUnterminatedBrace:     "unterminated brace expression",
+	ErrUnterminatedExtGlob:   "unterminated extended glob group",
+	ErrMismatchedGroup:       "mismatched group",
+	ErrNestedNegatedGroup:    "nested negated group",
+	ErrInvalidSequence:       "invalid sequence expression",
+	ErrSequenceTooLong:       "sequence expression too long",
+	ErrRegexp:                "regular expression error",
+	ErrSurroundingWhitespace: "surrounding whitespace",
+	ErrDanglingEscaper:       "dangling escaper",
+	ErrAmbiguousStar:         "ambiguous star",
+	ErrInvalidCaptureName:    "invalid capture name",
+	ErrDuplicateCaptureName:  "duplicate capture name",
+	ErrUnknownCapture:        "unknown capture",
+	ErrTooManyExpansions:     "too many expansions",
+}
+
+func (k PatternErrorKind) String() string {
+	if name, ok := patternErrorKindNames[k]; ok {
+		return name
+	}
+	return fmt.Sprintf("PatternErrorKind(%d)", uint8(k))
+}
+
+// PatternError describes a problem with a pattern, and where in the pattern it was found. It is the type of all errors
+// returned by Compile, CompileGlobSet and ExpandBraces, and of those returned by NewRewriter for invalid templates (in
+// which case Pattern is the template).
+type PatternError struct {
+	// The pattern that could not be compiled
+	Pattern string
+	// The index of the pattern within the slice passed to CompileGlobSet, or -1 if it was not compiled as part of a set
+	Index int
+	// The byte and rune offsets within the pattern of the offending token, or -1 if the problem is not at any
+	// particular position (eg. an invalid separator)
+	Offset     int
+	RuneOffset int
+	// The offending token
+	Token string
+	Kind  PatternErrorKind
+	// The underlying error, which describes the problem in more detail
+	Err error
+}
+
+func (e *PatternError) Error() string {
+	msg := fmt.Sprintf("%s in pattern \"%s\"", e.Err, e.Pattern)
+	if e.Offset >= 0 {
+		msg = fmt.Sprintf("%s at offset %d in pattern \"%s\"", e.Err, e.Offset, e.Pattern)
+	}
+	if e.Index >= 0 {
+		msg = fmt.Sprintf("%s (index %d)", msg, e.Index)
+	}
+	return msg
+}
+
+// Unwrap returns the underlying error
+func (e *PatternError) Unwrap() error {
+	return e.Err
+}
+
+// emptyPatternError is the underlying error of an ErrEmptyPattern PatternError. It unwraps to io.EOF, which was
+// returned for empty patterns before there were PatternErrors.
+type emptyPatternError struct{}
+
+func (emptyPatternError) Error() string {
+	return "empty pattern"
+}
+
+func (emptyPatternError) Unwrap() error {
+	return io.EOF
+}
+
+// Returns a PatternError of the given kind, whose pattern will be filled in by completePatternError
+func newPatternError(kind PatternErrorKind, offset int, token string, format string, args ...interface{}) *PatternError {
+	return &PatternError{
+		Index:  -1,
+		Offset: offset,
+		Token:  token,
+		Kind:   kind,
+		Err:    fmt.Errorf(format, args...),
+	}
+}
+
+// Fills in the pattern-dependent fields of a PatternError, shifting its offset by the passed amount (to account for any
+// leading text that was removed from the pattern before parsing). Errors of any other type are wrapped.
+func completePatternError(err error, pattern string, shift int) *PatternError {
+	patternErr, ok := err.(*PatternError)
+	if !ok {
+		patternErr = &PatternError{
+			Index:  -1,
+			Offset: -1,
+			Kind:   ErrRegexp,
+			Err:    err,
+		}
+	}
+
+	patternErr.Pattern = pattern
+	patternErr.RuneOffset = -1
+	if patternErr.Offset >= 0 {
+		patternErr.Offset += shift
+		if patternErr.Offset > len(pattern) {
+			patternErr.Offset = len(pattern)
+		}
+		patternErr.RuneOffset = utf8.RuneCountInString(pattern[:patternErr.Offset])
+	}
+	return patternErr
+}
@@ -0,0 +1,101 @@
+package ohmyglob
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPatternError(t *testing.T) {
+	extGlobOptions := &Options{
+		Separator:    '/',
+		MatchAtStart: true,
+		MatchAtEnd:   true,
+		ExtGlob:      true,
+	}
+	strictOptions := &Options{
+		Separator:    '/',
+		MatchAtStart: true,
+		MatchAtEnd:   true,
+		Strict:       true,
+	}
+	cases := []struct {
+		pattern    string
+		options    *Options
+		kind       PatternErrorKind
+		offset     int
+		runeOffset int
+		token      string
+	}{
+		{`foo[abc`, strictOptions, ErrUnterminatedCharClass, 3, 3, `[abc`},
+		{`∆/[[:alpha:]`, strictOptions, ErrUnterminatedCharClass, 4, 2, `[[:alpha:]`},
+		{`foo/[z-a]`, nil, ErrInvalidRange, 5, 5, `z-a`},
+		{`∆/[a-cz-a]`, nil, ErrInvalidRange, 8, 6, `z-a`},
+		{`[!\z-\a]`, nil, ErrInvalidRange, 2, 2, `\z-\a`},
+		{`foo/[a[:alphabet:]]`, nil, ErrUnknownCharClass, 6, 6, `[:alphabet:]`},
+		{`!∆[[:nope:]]`, nil, ErrUnknownCharClass, 5, 3, `[:nope:]`},
+		{`a/{b,{c}`, strictOptions, ErrUnterminatedBrace, 2, 2, `{`},
+		{`x{0..1000000}`, nil, ErrSequenceTooLong, 1, 1, `{0..1000000}`},
+		{`x{1..5..0}`, nil, ErrInvalidSequence, 1, 1, `{1..5..0}`},
+		{`∆/∆{a`, strictOptions, ErrUnterminatedBrace, 7, 3, `{`},
+		{`a+(b|c`, extGlobOptions, ErrUnterminatedExtGlob, 1, 1, `+(`},
+		{`@(a|!(b))`, extGlobOptions, ErrNestedNegatedGroup, 4, 4, `!(`},
+		{`{a,@(b})`, extGlobOptions, ErrMismatchedGroup, 6, 6, `}`},
+		{`!`, nil, ErrEmptyPattern, -1, -1, ``},
+		{`a/{9lives:*}`, nil, ErrInvalidCaptureName, 2, 2, `{9lives:*}`},
+		{`{my-svc:**}`, nil, ErrInvalidCaptureName, 0, 0, `{my-svc:**}`},
+		{`{:*}`, nil, ErrInvalidCaptureName, 0, 0, `{:*}`},
+		{`{x:*}/∆/{x:**}`, nil, ErrDuplicateCaptureName, 10, 8, `{x:**}`},
+	}
+
+	for _, c := range cases {
+		_, err := Compile(c.pattern, c.options)
+		var patternErr *PatternError
+		if !assert.True(t, errors.As(err, &patternErr), "Compiling `%s` should fail with a PatternError", c.pattern) {
+			continue
+		}
+		assert.Equal(t, c.pattern, patternErr.Pattern)
+		assert.Equal(t, -1, patternErr.Index)
+		assert.Equal(t, c.kind, patternErr.Kind, "Unexpected kind for `%s`", c.pattern)
+		assert.Equal(t, c.offset, patternErr.Offset, "Unexpected offset for `%s`", c.pattern)
+		assert.Equal(t, c.runeOffset, patternErr.RuneOffset, "Unexpected rune offset for `%s`", c.pattern)
+		assert.Equal(t, c.token, patternErr.Token, "Unexpected token for `%s`", c.pattern)
+		assert.Contains(t, err.Error(), c.pattern)
+	}
+
+	// Empty patterns are described as such, but still unwrap to io.EOF
+	_, err := Compile(``, nil)
+	assert.True(t, errors.Is(err, io.EOF))
+	assert.Equal(t, `empty pattern in pattern ""`, err.Error())
+
+	_, err = Compile(`foo`, &Options{Separator: '*'})
+	var patternErr *PatternError
+	if assert.True(t, errors.As(err, &patternErr)) {
+		assert.Equal(t, ErrInvalidSeparator, patternErr.Kind)
+		assert.Equal(t, -1, patternErr.Offset)
+	}
+}
+
+func TestPatternError_GlobSet(t *testing.T) {
+	_, err := CompileGlobSet([]string{`foo/**`, `!foo/bar`, `foo/[b-a]`}, DefaultOptions)
+	var patternErr *PatternError
+	if assert.True(t, errors.As(err, &patternErr)) {
+		assert.Equal(t, 2, patternErr.Index)
+		assert.Equal(t, `foo/[b-a]`, patternErr.Pattern)
+		assert.Equal(t, ErrInvalidRange, patternErr.Kind)
+		assert.Equal(t, 5, patternErr.Offset)
+		assert.Contains(t, err.Error(), "index 2")
+	}
+}
+
+func TestPatternError_ExpandBraces(t *testing.T) {
+	_, err := ExpandBraces(`!!a/{b,c`, &Options{Separator: '/', Strict: true})
+	var patternErr *PatternError
+	if assert.True(t, errors.As(err, &patternErr)) {
+		assert.Equal(t, ErrUnterminatedBrace, patternErr.Kind)
+		assert.Equal(t, 4, patternErr.Offset)
+		assert.Equal(t, `!!a/{b,c`, patternErr.Pattern)
+	}
+}
@@ -0,0 +1,249 @@
+package ohmyglob
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// negatedGroupPart is a component of a pattern containing negated extended glob groups. A pattern is split into
+// alternating regular and negated parts, starting and ending with a regular part (which may be empty).
+type negatedGroupPart struct {
+	// Matches the entire substring that the part covers (for a negated part, the substring must NOT match)
+	*regexp.Regexp
+	negated bool
+	// Simulates the regular expression anchored only at its start, to find every prefix of some text that it matches in
+	// a single pass over the text (see ends)
+	automaton *setAutomaton
+}
+
+// negatedGroupGlob is a Glob containing negated extended glob groups (eg. "!(*.test).go"). Go's regular expressions
+// can't express "anything except", so the pattern is split around its negated groups and matched by searching for a
+// division of the input that satisfies every part. The embedded globImpl's regular expression approximates each
+// negated group as a wildcard, and is used to rule out most non-matching input cheaply.
+type negatedGroupGlob struct {
+	*globImpl
+	parts []negatedGroupPart
+	// The runes which a negated group never matches
+	excluded []rune
+}
+
+func newNegatedGroupGlob(glob *globImpl) (*negatedGroupGlob, error) {
+	state := glob.parserState
+	options := state.options
+	g := &negatedGroupGlob{
+		globImpl: glob,
+		parts:    make([]negatedGroupPart, 0, state.negatedGroups*2+1),
+		excluded: options.excludedRunes(),
+	}
+
+	// Split the processed tokens into parts, ensuring that negated parts are always surrounded by regular ones
+	regexParts := make([]string, 0, cap(g.parts))
+	negatedParts := make([]bool, 0, cap(g.parts))
+	appendPart := func(buf *bytes.Buffer, negated bool) {
+		if negated && (len(negatedParts) == 0 || negatedParts[len(negatedParts)-1]) {
+			regexParts = append(regexParts, "")
+			negatedParts = append(negatedParts, false)
+		}
+		regexParts = append(regexParts, buf.String())
+		negatedParts = append(negatedParts, negated)
+		buf.Reset()
+	}
+
+	buf := new(bytes.Buffer)
+	inNegatedPart := false
+	for _, t := range state.processedTokens {
+		if t.tokenType == tcExtGlobOpen && t.token == "!(" {
+			appendPart(buf, inNegatedPart)
+			inNegatedPart = true
+			continue
+		} else if inNegatedPart && !t.negatedGroup {
+			appendPart(buf, true)
+			inNegatedPart = false
+		}
+		buf.Write(t.contents.Bytes())
+	}
+	appendPart(buf, inNegatedPart)
+	if inNegatedPart {
+		regexParts = append(regexParts, "")
+		negatedParts = append(negatedParts, false)
+	}
+
+	for i, regexPart := range regexParts {
+		if negatedParts[i] {
+			regexPart = "(?:" + regexPart + ")"
+		} else {
+			// The text the part covers (excluding any unanchored prefix or suffix) is captured by the first group
+			regexPart = "(" + regexPart + ")"
+			if i == 0 && !options.MatchAtStart {
+				regexPart = "(?s:.*?)" + regexPart
+			}
+			if i == len(regexParts)-1 && !options.MatchAtEnd {
+				regexPart = regexPart + "(?s:.*)"
+			}
+		}
+
+		re, err := regexp.Compile(regexFlags(options) + "^" + regexPart + "$")
+		if err != nil {
+			return nil, err
+		}
+		prefixRe, err := regexp.Compile(regexFlags(options) + "^" + regexPart)
+		if err != nil {
+			return nil, err
+		}
+		automaton, err := newSetAutomaton([]*regexp.Regexp{prefixRe})
+		if err != nil {
+			return nil, err
+		}
+		g.parts = append(g.parts, negatedGroupPart{
+			Regexp:    re,
+			negated:   negatedParts[i],
+			automaton: automaton,
+		})
+	}
+
+	Logger.Tracef("[ohmyglob:Glob] Split \"%s\" into %d parts around negated groups", glob.globPattern, len(g.parts))
+	return g, nil
+}
+
+// isExcluded reports whether the rune can't be matched by a negated group
+func (g *negatedGroupGlob) isExcluded(r rune) bool {
+	for _, excluded := range g.excluded {
+		if r == excluded {
+			return true
+		}
+	}
+	return false
+}
+
+// ends reports, for each position from start to end, whether the part's regular expression matches the text from start
+// to that position. The text is read once, rather than matching the expression against each prefix in turn (which
+// would take quadratic time).
+func (p *negatedGroupPart) ends(s string, start, end int) []bool {
+	ends := make([]bool, end-start+1)
+	state := p.automaton.startState()
+	pos := start
+	for len(state.ids) > 0 || len(state.matches) > 0 {
+		ends[pos-start] = len(state.matches) > 0
+		if pos == end {
+			ends[pos-start] = ends[pos-start] || len(p.automaton.endMatches(state)) > 0
+			break
+		}
+		r, width := utf8.DecodeRuneInString(s[pos:end])
+		state = p.automaton.step(state, r)
+		pos += width
+	}
+	return ends
+}
+
+// matchFrom reports whether the parts from partIdx onwards match the input from position pos to its end. Results are
+// memoised, as the same (part, position) pair can be reached by many different divisions of the input; the memo holds
+// the end of the text covered by the part in a successful division, or -1 if there is none. The ends of the text each
+// part could cover are found in a single pass (see ends), so matching takes time linear in the length of the input
+// for each position at which a part can start.
+func (g *negatedGroupGlob) matchFrom(s string, partIdx, pos int, memo map[int]int) bool {
+	if partIdx == len(g.parts) {
+		return pos == len(s)
+	}
+
+	key := partIdx*(len(s)+1) + pos
+	if end, ok := memo[key]; ok {
+		return end >= 0
+	}
+
+	end := -1
+	part := g.parts[partIdx]
+	if part.negated {
+		// A negated group matches any text which does not contain a separator (or other excluded rune), and does not
+		// match the group
+		limit := len(s)
+		if idx := strings.IndexFunc(s[pos:], g.isExcluded); idx >= 0 {
+			limit = pos + idx
+		}
+		matched := part.ends(s, pos, limit)
+		for i := pos; i <= limit && end < 0; i++ {
+			if (i == len(s) || utf8.RuneStart(s[i])) && !matched[i-pos] && g.matchFrom(s, partIdx+1, i, memo) {
+				end = i
+			}
+		}
+	} else if partIdx == len(g.parts)-1 {
+		if part.MatchString(s[pos:]) {
+			end = len(s)
+		}
+	} else {
+		// Like the wildcards within it, a regular part is greedy, covering as much of the input as it can
+		matched := part.ends(s, pos, len(s))
+		for i := len(s); i >= pos && end < 0; i-- {
+			if matched[i-pos] && g.matchFrom(s, partIdx+1, i, memo) {
+				end = i
+			}
+		}
+	}
+
+	memo[key] = end
+	return end >= 0
+}
+
+func (g *negatedGroupGlob) MatchString(s string) bool {
+	if !g.globImpl.MatchString(s) {
+		return false
+	}
+	return g.matchFrom(s, 0, 0, make(map[int]int))
+}
+
+func (g *negatedGroupGlob) Match(b []byte) bool {
+	return g.MatchString(string(b))
+}
+
+func (g *negatedGroupGlob) MatchReader(r io.RuneReader) bool {
+	s, err := readAll(r, 0)
+	return err == nil && g.MatchString(s)
+}
+
+// FindStringSubmatchIndex finds a division of the input that satisfies every part, and collects the submatches of
+// each regular part within it
+func (g *negatedGroupGlob) FindStringSubmatchIndex(s string) []int {
+	memo := make(map[int]int)
+	if !g.globImpl.MatchString(s) || !g.matchFrom(s, 0, 0, memo) {
+		return nil
+	}
+
+	result := make([]int, 2, 2*(g.NumSubexp()+1))
+	pos := 0
+	for partIdx, part := range g.parts {
+		end := memo[partIdx*(len(s)+1)+pos]
+		if !part.negated {
+			loc := part.FindStringSubmatchIndex(s[pos:end])
+			for i := range loc {
+				if loc[i] >= 0 {
+					loc[i] += pos
+				}
+			}
+			if partIdx == 0 {
+				result[0] = loc[2]
+			}
+			result[1] = loc[3]
+			result = append(result, loc[4:]...)
+		}
+		pos = end
+	}
+	return result
+}
+
+func (g *negatedGroupGlob) FindSubmatchIndex(b []byte) []int {
+	return g.FindStringSubmatchIndex(string(b))
+}
+
+func (g *negatedGroupGlob) FindStringSubmatch(s string) []string {
+	return submatchStrings(s, g.FindStringSubmatchIndex(s))
+}
+
+func (g *negatedGroupGlob) Captures(s string) map[string]string {
+	return namedCaptures(g.SubexpNames(), g.FindStringSubmatch(s))
+}
+
+func (g *negatedGroupGlob) FindSubmatch(b []byte) [][]byte {
+	return submatchBytes(b, g.FindSubmatchIndex(b))
+}
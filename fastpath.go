@@ -0,0 +1,179 @@
+package ohmyglob
+
+import (
+	"bytes"
+	"io"
+	"strings"
+)
+
+// literalGlob is a Glob for a pattern without any wildcards, which matches exactly one string
+type literalGlob struct {
+	*globImpl
+	literal      string
+	literalBytes []byte
+}
+
+// prefixGlob is a Glob for a pattern such as "foo/bar/**", which matches a literal and anything beneath it
+type prefixGlob struct {
+	*globImpl
+	prefix      string
+	prefixBytes []byte
+	separator   string
+	sepBytes    []byte
+	// Input longer than the prefix must be longer than the prefix and separator together
+	minLenBeyond int
+}
+
+// suffixGlob is a Glob for a pattern such as "**/*.go" or "**/Makefile", which matches anything whose last segment ends
+// with (or, if the pattern has no star, is) a literal
+type suffixGlob struct {
+	*globImpl
+	suffix      string
+	suffixBytes []byte
+	separator   string
+	sepBytes    []byte
+	// Set to true if the last segment may have text before the suffix (ie. the pattern has a star)
+	star bool
+}
+
+// newFastPathGlob returns a specialised Glob if the processed tokens of the glob form one of the shapes that can be
+// matched by simple comparisons, or nil otherwise
+func newFastPathGlob(glob *globImpl) Glob {
+	state := glob.parserState
+	options := state.options
+	tokens := state.processedTokens
+	if !options.MatchAtStart || !options.MatchAtEnd || options.CaseFolding != CaseSensitive ||
+		options.AltSeparator != 0 || options.VolumeRoots || state.negatedGroups > 0 || len(tokens) == 0 {
+		return nil
+	}
+	separator := string(options.Separator)
+
+	// Returns the literal text of the tokens, and whether they consisted only of literals and separators
+	literalText := func(tokens []processedToken) (string, bool) {
+		buf := new(strings.Builder)
+		for _, t := range tokens {
+			if t.tokenType != tcLiteral && t.tokenType != tcSeparator {
+				return "", false
+			}
+			buf.WriteString(t.token)
+		}
+		return buf.String(), true
+	}
+
+	if literal, ok := literalText(tokens); ok {
+		return &literalGlob{
+			globImpl:     glob,
+			literal:      literal,
+			literalBytes: []byte(literal),
+		}
+	}
+
+	last := len(tokens) - 1
+	if tokens[last].tokenType == tcGlobStar && last > 0 {
+		// The globstar matches nothing, or a separator followed by at least one character
+		if prefix, ok := literalText(tokens[:last]); ok {
+			return &prefixGlob{
+				globImpl:     glob,
+				prefix:       prefix,
+				prefixBytes:  []byte(prefix),
+				separator:    separator,
+				sepBytes:     []byte(separator),
+				minLenBeyond: len(prefix) + len(separator),
+			}
+		}
+	}
+
+	if tokens[0].tokenType == tcGlobStar && last > 0 {
+		// The globstar matches nothing, or at least one character followed by a separator
+		star := tokens[1].tokenType == tcStar
+		rest := tokens[1:]
+		if star {
+			rest = tokens[2:]
+		}
+		if suffix, ok := literalText(rest); ok && len(rest) == 1 && !strings.Contains(suffix, separator) &&
+			(star || suffix != "") {
+			return &suffixGlob{
+				globImpl:    glob,
+				suffix:      suffix,
+				suffixBytes: []byte(suffix),
+				separator:   separator,
+				sepBytes:    []byte(separator),
+				star:        star,
+			}
+		}
+	}
+
+	return nil
+}
+
+func (g *literalGlob) MatchString(s string) bool {
+	return s == g.literal
+}
+
+func (g *literalGlob) Match(b []byte) bool {
+	return bytes.Equal(b, g.literalBytes)
+}
+
+func (g *literalGlob) MatchReader(r io.RuneReader) bool {
+	s, err := readAll(r, 0)
+	return err == nil && g.MatchString(s)
+}
+
+func (g *prefixGlob) MatchString(s string) bool {
+	if len(s) == len(g.prefix) {
+		return s == g.prefix
+	}
+	// The globstar doesn't match newlines
+	return len(s) > g.minLenBeyond && strings.HasPrefix(s, g.prefix) &&
+		strings.HasPrefix(s[len(g.prefix):], g.separator) && strings.IndexByte(s[len(g.prefix):], '\n') < 0
+}
+
+func (g *prefixGlob) Match(b []byte) bool {
+	if len(b) == len(g.prefixBytes) {
+		return bytes.Equal(b, g.prefixBytes)
+	}
+	return len(b) > g.minLenBeyond && bytes.HasPrefix(b, g.prefixBytes) &&
+		bytes.HasPrefix(b[len(g.prefixBytes):], g.sepBytes) && bytes.IndexByte(b[len(g.prefixBytes):], '\n') < 0
+}
+
+func (g *prefixGlob) MatchReader(r io.RuneReader) bool {
+	s, err := readAll(r, 0)
+	return err == nil && g.MatchString(s)
+}
+
+func (g *suffixGlob) MatchString(s string) bool {
+	sepIdx := strings.LastIndex(s, g.separator)
+	if sepIdx == 0 || strings.IndexByte(s[:sepIdx+1], '\n') >= 0 {
+		// Anything before the last segment must be at least one character (other than a newline, which the globstar
+		// doesn't match), followed by the separator
+		return false
+	}
+	lastSegment := s
+	if sepIdx > 0 {
+		lastSegment = s[sepIdx+len(g.separator):]
+	}
+	if g.star {
+		return strings.HasSuffix(lastSegment, g.suffix)
+	}
+	return lastSegment == g.suffix
+}
+
+func (g *suffixGlob) Match(b []byte) bool {
+	sepIdx := bytes.LastIndex(b, g.sepBytes)
+	if sepIdx == 0 || bytes.IndexByte(b[:sepIdx+1], '\n') >= 0 {
+		return false
+	}
+	lastSegment := b
+	if sepIdx > 0 {
+		lastSegment = b[sepIdx+len(g.sepBytes):]
+	}
+	if g.star {
+		return bytes.HasSuffix(lastSegment, g.suffixBytes)
+	}
+	return bytes.Equal(lastSegment, g.suffixBytes)
+}
+
+func (g *suffixGlob) MatchReader(r io.RuneReader) bool {
+	s, err := readAll(r, 0)
+	return err == nil && g.MatchString(s)
+}
@@ -0,0 +1,135 @@
+package ohmyglob
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/obeattie/ohmyglob/internal/equivalence"
+	"github.com/stretchr/testify/assert"
+)
+
+// Returns the regular expression that a Glob is equivalent to
+func globRegexp(glob Glob) *regexp.Regexp {
+	re, _ := glob.(automatable).automatonRegexp()
+	return re
+}
+
+func TestFastPathGlob(t *testing.T) {
+	// Maps to a pair of (should, shouldn't) string slices
+	expectations := map[string][2][]string{
+		`foo/bar.go`: [2][]string{
+			[]string{`foo/bar.go`},
+			[]string{`foo/bar.gox`, `foo/bar`, `xfoo/bar.go`},
+		},
+		`foo\*/\?`: [2][]string{
+			[]string{`foo*/?`},
+			[]string{`foox/a`},
+		},
+		`foo/bar/**`: [2][]string{
+			[]string{`foo/bar`, `foo/bar/a`, `foo/bar/a/b`},
+			[]string{`foo/bar/`, `foo/barx`, `foo/ba`, `foo`, "foo/bar/a\nb"},
+		},
+		`**/*.go`: [2][]string{
+			[]string{`a.go`, `.go`, `a/b.go`, `a/b/c.go`, "a/b\nc.go"},
+			[]string{`/a.go`, `a.go/b`, `a/b.gox`, "a\nb/c.go"},
+		},
+		`**/Makefile`: [2][]string{
+			[]string{`Makefile`, `a/Makefile`},
+			[]string{`aMakefile`, `a/xMakefile`, `/Makefile`},
+		},
+	}
+	types := map[string]Glob{
+		`foo/bar.go`:  &literalGlob{},
+		`foo\*/\?`:    &literalGlob{},
+		`foo/bar/**`:  &prefixGlob{},
+		`**/*.go`:     &suffixGlob{},
+		`**/Makefile`: &suffixGlob{},
+	}
+
+	for pattern, s := range expectations {
+		glob, err := Compile(pattern, DefaultOptions)
+		if !assert.NoError(t, err, "Compiling `%s`", pattern) {
+			continue
+		}
+		assert.IsType(t, types[pattern], glob, "Unexpected implementation for `%s`", pattern)
+		assert.Equal(t, pattern, glob.String())
+
+		for _, should := range s[0] {
+			assert.True(t, glob.MatchString(should), "Glob `%s` should match `%s`", pattern, should)
+			assert.True(t, glob.Match([]byte(should)), "Glob `%s` should match `%s`", pattern, should)
+			assert.True(t, glob.MatchReader(strings.NewReader(should)), "Glob `%s` should match `%s`", pattern, should)
+		}
+		for _, shouldnt := range s[1] {
+			assert.False(t, glob.MatchString(shouldnt), "Glob `%s` should not match `%s`", pattern, shouldnt)
+			assert.False(t, glob.Match([]byte(shouldnt)), "Glob `%s` should not match `%s`", pattern, shouldnt)
+		}
+	}
+
+	// Negation is unaffected, and case-insensitive globs are not matched by simple comparison
+	glob, err := Compile(`!foo/**`, DefaultOptions)
+	if assert.NoError(t, err) {
+		assert.IsType(t, &prefixGlob{}, glob)
+		assert.True(t, glob.IsNegative())
+		assert.Equal(t, `!foo/**`, glob.String())
+	}
+	glob, err = Compile(`foo/bar`, &Options{Separator: '/', MatchAtStart: true, MatchAtEnd: true, CaseFolding: FoldASCII})
+	if assert.NoError(t, err) {
+		assert.True(t, glob.MatchString(`FOO/bar`))
+	}
+}
+
+// Compares the results of the fast paths with those of the equivalent regular expression, for random patterns and
+// inputs
+func TestFastPathGlob_Equivalence(t *testing.T) {
+	patternParts := []string{`a`, `b`, `.c`, `/`, `**/`, `/**`, `**/*`, `\*`}
+	inputParts := []string{`a`, `b`, `.c`, `/`, `/`, `*`, "\n"}
+	equivalence.Check(equivalence.Parts{Parts: patternParts, Max: 4}, equivalence.Parts{Parts: inputParts, Max: 8},
+		func(pattern string) func(string) {
+			glob, err := Compile(pattern, DefaultOptions)
+			if err != nil {
+				return nil
+			}
+			switch glob.(type) {
+			case *literalGlob, *prefixGlob, *suffixGlob:
+			default:
+				return nil
+			}
+
+			re := globRegexp(glob)
+			return func(input string) {
+				expected := re.MatchString(input)
+				assert.Equal(t, expected, glob.MatchString(input), "Glob `%s` should match `%s`: %v (regex `%s`)",
+					pattern, input, expected, re.String())
+				assert.Equal(t, expected, glob.Match([]byte(input)), "Glob `%s` should match `%s`: %v (regex `%s`)",
+					pattern, input, expected, re.String())
+			}
+		})
+}
+
+var fastPathBenchmarkPatterns = []string{`src/cmd/main.go`, `vendor/**`, `**/*.go`}
+
+func BenchmarkMatching_FastPath(b *testing.B) {
+	for _, pattern := range fastPathBenchmarkPatterns {
+		glob, err := Compile(pattern, nil)
+		assert.NoError(b, err)
+		b.Run(pattern, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				glob.MatchString(benchmarkPaths[i%len(benchmarkPaths)])
+			}
+		})
+	}
+}
+
+func BenchmarkMatching_FastPathRegexp(b *testing.B) {
+	for _, pattern := range fastPathBenchmarkPatterns {
+		glob, err := Compile(pattern, nil)
+		assert.NoError(b, err)
+		re := globRegexp(glob)
+		b.Run(pattern, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				re.MatchString(benchmarkPaths[i%len(benchmarkPaths)])
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package ohmyglob
+
+import (
+	"errors"
+	"io/fs"
+	"sort"
+	"strings"
+)
+
+// walkRootable is implemented by Globs which can report the deepest directory beneath which all of the paths they match
+// lie
+type walkRootable interface {
+	// Returns the directory, which is "." if the Glob may match any path (or a path which is not valid in an fs.FS if
+	// the Glob can't match any path in one)
+	walkRoot() string
+}
+
+func (g *globImpl) walkRoot() string {
+	return g.root
+}
+
+// findWalkRoot returns the deepest directory beneath which all of the paths matched by the processed tokens lie,
+// derived from the literal prefix of the pattern. Paths in an fs.FS are always separated by slashes, so patterns with
+// any other separator (or an additional one, or which don't match at the start of the input, or which fold case) may
+// match any path. A pattern which doesn't match at the end of the input can match paths which extend its last segment
+// (eg. "src/a" matches "src/a.go"), so only the segments of its prefix which are followed by a separator are used.
+func findWalkRoot(state *parserState) string {
+	options := state.options
+	if options.Separator != '/' || options.AltSeparator != 0 || !options.MatchAtStart ||
+		options.CaseFolding != CaseSensitive {
+		return "."
+	}
+
+	prefix := new(strings.Builder)
+	wholeSegment := true
+	lastType := tcUnknown
+	for _, t := range state.processedTokens {
+		if t.tokenType != tcLiteral && t.tokenType != tcSeparator {
+			// A globstar which begins a segment following the prefix matches whole segments, so the prefix is itself a
+			// directory. So does a trailing globstar, whose regular expression begins with the separator (even if it
+			// is glued to the prefix, as in "src**"); but one glued to the prefix and followed by more of the pattern
+			// (as in "src**/x", which matches "srcfoo/x") extends the prefix's last segment.
+			wholeSegment = t.tokenType == tcGlobStar && (lastType == tcUnknown || lastType == tcSeparator ||
+				strings.HasPrefix(t.contents.String(), "(?:"+state.escapedSeparator))
+			break
+		}
+		prefix.WriteString(t.token)
+		lastType = t.tokenType
+	}
+
+	root := prefix.String()
+	if !wholeSegment || !options.MatchAtEnd {
+		root = root[:strings.LastIndexByte(root, '/')+1]
+	}
+	root = strings.TrimSuffix(root, "/")
+	if root == "" {
+		return "."
+	}
+	return root
+}
+
+// walkRoots returns the directories beneath which all of the paths matched by the matcher lie, excluding any which are
+// within others
+func walkRoots(matcher GlobMatcher) []string {
+	var roots []string
+	switch m := matcher.(type) {
+	case *globSetImpl:
+		for _, glob := range m.globs {
+			if glob.IsNegative() {
+				// Negative globs only exclude paths
+				continue
+			}
+			if g, ok := glob.(walkRootable); ok {
+				roots = append(roots, g.walkRoot())
+			} else {
+				roots = append(roots, ".")
+			}
+		}
+	case walkRootable:
+		roots = append(roots, m.walkRoot())
+	default:
+		roots = append(roots, ".")
+	}
+
+	sort.Strings(roots)
+	result := roots[:0]
+	for _, root := range roots {
+		if !fs.ValidPath(root) {
+			continue
+		}
+		if root == "." {
+			return []string{"."}
+		}
+		if n := len(result); n > 0 && (root == result[n-1] || strings.HasPrefix(root, result[n-1]+"/")) {
+			continue
+		}
+		result = append(result, root)
+	}
+	return result
+}
+
+// ErrNegativeGlob is returned by WalkFS and GlobFS when the matcher is a single negative Glob, which only excludes
+// paths (use a GlobSet to exclude paths from those matched by other patterns)
+var ErrNegativeGlob = errors.New("a negative glob can't be walked on its own")
+
+// WalkFS calls fn for each path in fsys matched by the matcher (which is usually a Glob or GlobSet), in lexical order
+// within each directory that is walked. Only the directories named by the literal prefixes of the patterns (eg. "src"
+// for "src/**/*.go") are walked, rather than the whole of fsys, and directories beneath which the matcher can't match
+// anything (according to its CouldMatchPrefix method, if it has one) are skipped. Patterns are matched against the
+// slash-separated paths used by fs.FS (eg. "src/main.go"); the root "." itself is never matched. If fn returns
+// fs.SkipAll, walking stops without error; any other error stops walking and is returned. A negative Glob can't be
+// walked on its own, and ErrNegativeGlob is returned.
+func WalkFS(fsys fs.FS, matcher GlobMatcher, fn func(path string, d fs.DirEntry) error) error {
+	if glob, ok := matcher.(Glob); ok && glob.IsNegative() {
+		return ErrNegativeGlob
+	}
+
+	// fs.WalkDir returns nil rather than fs.SkipAll, so whether fn asked to stop is recorded to skip the remaining roots
+	skippedAll := false
+	for _, root := range walkRoots(matcher) {
+		err := fs.WalkDir(fsys, root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if path == root && errors.Is(err, fs.ErrNotExist) {
+					// A root which doesn't exist has no matching paths
+					return nil
+				}
+				return err
+			}
+			if path != "." && matcher.MatchString(path) {
+				if err := fn(path, d); err != nil {
+					skippedAll = err == fs.SkipAll
+					return err
+				}
+			}
+			if p, ok := matcher.(interface{ CouldMatchPrefix(string) bool }); ok && d.IsDir() && path != "." &&
+				!p.CouldMatchPrefix(path) {
+				return fs.SkipDir
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		} else if skippedAll {
+			return nil
+		}
+	}
+	return nil
+}
+
+// GlobFS returns the sorted paths in fsys matched by the matcher (which is usually a Glob or GlobSet); see WalkFS
+func GlobFS(fsys fs.FS, matcher GlobMatcher) ([]string, error) {
+	var paths []string
+	err := WalkFS(fsys, matcher, func(path string, d fs.DirEntry) error {
+		paths = append(paths, path)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
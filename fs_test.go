@@ -0,0 +1,176 @@
+package ohmyglob
+
+import (
+	"errors"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testFS = fstest.MapFS{
+	"README.md":                     {},
+	"src/main.go":                   {},
+	"src/main_test.go":              {},
+	"src/util/strings.go":           {},
+	"src/util/strings_test.go":      {},
+	"src/vendor/lib/lib.go":         {},
+	"docs/guide.md":                 {},
+	"docs/api/index.md":             {},
+	"services/auth/config.yaml":     {},
+	"services/billing/config.yaml":  {},
+	"services/billing/extra/x.yaml": {},
+}
+
+// openRecorder records the paths opened in the wrapped filesystem
+type openRecorder struct {
+	fs.FS
+	opened []string
+}
+
+func (r *openRecorder) Open(name string) (fs.File, error) {
+	r.opened = append(r.opened, name)
+	return r.FS.Open(name)
+}
+
+func TestGlobFS(t *testing.T) {
+	cases := map[string][]string{
+		`src/**/*.go`:                {`src/main.go`, `src/main_test.go`, `src/util/strings.go`, `src/util/strings_test.go`, `src/vendor/lib/lib.go`},
+		`**/*.md`:                    {`README.md`, `docs/api/index.md`, `docs/guide.md`},
+		`services/*/config.yaml`:     {`services/auth/config.yaml`, `services/billing/config.yaml`},
+		`docs/**`:                    {`docs`, `docs/api`, `docs/api/index.md`, `docs/guide.md`},
+		`src/util`:                   {`src/util`},
+		`nope/**`:                    nil,
+		`/src/**`:                    nil,
+		`{src,docs}/*.{go,md}`:       {`docs/guide.md`, `src/main.go`, `src/main_test.go`},
+		`services/{svc:*}/**/*.yaml`: {`services/auth/config.yaml`, `services/billing/config.yaml`, `services/billing/extra/x.yaml`},
+		// Globstars glued to the prefix extend its last segment, unless they end the pattern
+		`sr**/*.go`:      {`src/main.go`, `src/main_test.go`, `src/util/strings.go`, `src/util/strings_test.go`, `src/vendor/lib/lib.go`},
+		`services/a**/*`: {`services/auth`, `services/auth/config.yaml`},
+		`src/util**`:     {`src/util`, `src/util/strings.go`, `src/util/strings_test.go`},
+	}
+	for pattern, expected := range cases {
+		glob, err := Compile(pattern, nil)
+		assert.NoError(t, err)
+		paths, err := GlobFS(testFS, glob)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, paths, "Unexpected paths for `%s`", pattern)
+	}
+
+	set, err := CompileGlobSet([]string{`src/**/*.go`, `!**/*_test.go`, `!src/vendor/**`, `docs/*.md`}, nil)
+	assert.NoError(t, err)
+	paths, err := GlobFS(testFS, set)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{`docs/guide.md`, `src/main.go`, `src/util/strings.go`}, paths)
+
+	// Patterns which don't match at the end of the input also match paths extending their last segment
+	prefixFS := fstest.MapFS{
+		"src/a.go":  {},
+		"srcx/b.go": {},
+		"src2":      {},
+	}
+	for pattern, expected := range map[string][]string{
+		`src`:   {`src`, `src/a.go`, `src2`, `srcx`, `srcx/b.go`},
+		`src/a`: {`src/a.go`},
+	} {
+		glob, err := Compile(pattern, &Options{Separator: '/', MatchAtStart: true})
+		assert.NoError(t, err)
+		paths, err := GlobFS(prefixFS, glob)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, paths, "Unexpected paths for `%s`", pattern)
+	}
+}
+
+func TestGlobFS_Roots(t *testing.T) {
+	cases := map[string][]string{
+		`src/util/*.go`:          {`src/util`},
+		`src/**/*.go`:            {`src`},
+		`services/*/config.yaml`: {`services`},
+		`src/main.go`:            {`src/main.go`},
+		`*.md`:                   {`.`},
+		`**/*.md`:                {`.`},
+		`src**/x`:                {`.`},
+		`services/a**/*`:         {`services`},
+		`src/util**`:             {`src/util`},
+	}
+	for pattern, expected := range cases {
+		glob, err := Compile(pattern, nil)
+		assert.NoError(t, err)
+		recorder := &openRecorder{FS: testFS}
+		_, err = GlobFS(recorder, glob)
+		assert.NoError(t, err)
+		if assert.NotEmpty(t, recorder.opened) {
+			// The root is opened first; everything else that's opened is beneath it
+			assert.Equal(t, expected[0], recorder.opened[0], "Unexpected root for `%s`", pattern)
+			for _, opened := range recorder.opened {
+				assert.True(t, expected[0] == "." || opened == expected[0] || strings.HasPrefix(opened, expected[0]+"/"),
+					"`%s` should not be opened for `%s`", opened, pattern)
+			}
+		}
+	}
+
+	// Nested roots are only walked once
+	set, err := CompileGlobSet([]string{`src/**/*.go`, `src/util/*.go`, `!docs/**`}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{`src`}, walkRoots(set))
+	// Patterns which don't match at the start (or use another separator) may match anywhere
+	glob, err := Compile(`src/*.go`, &Options{Separator: '/', MatchAtEnd: true})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{`.`}, walkRoots(glob))
+	// Patterns which don't match at the end are rooted at the last whole segment of their prefix
+	for pattern, expected := range map[string]string{`src`: `.`, `src/a`: `src`, `src/**`: `.`, `src/*.go`: `src`} {
+		glob, err := Compile(pattern, &Options{Separator: '/', MatchAtStart: true})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{expected}, walkRoots(glob), "Unexpected root for `%s`", pattern)
+	}
+}
+
+func TestWalkFS(t *testing.T) {
+	glob, err := Compile(`**/*.go`, nil)
+	assert.NoError(t, err)
+
+	var visited []string
+	err = WalkFS(testFS, glob, func(path string, d fs.DirEntry) error {
+		visited = append(visited, path)
+		assert.False(t, d.IsDir())
+		if len(visited) == 2 {
+			return fs.SkipAll
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{`src/main.go`, `src/main_test.go`}, visited)
+
+	// Stopping the walk also skips the remaining roots
+	set, err := CompileGlobSet([]string{`docs/*`, `src/*`}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{`docs`, `src`}, walkRoots(set))
+	visited = nil
+	err = WalkFS(testFS, set, func(path string, d fs.DirEntry) error {
+		visited = append(visited, path)
+		return fs.SkipAll
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{`docs/api`}, visited)
+
+	failure := errors.New("failure")
+	err = WalkFS(testFS, glob, func(path string, d fs.DirEntry) error {
+		return failure
+	})
+	assert.Equal(t, failure, err)
+}
+
+func TestWalkFS_NegativeGlob(t *testing.T) {
+	glob, err := Compile(`!**/*_test.go`, nil)
+	assert.NoError(t, err)
+	err = WalkFS(testFS, glob, func(path string, d fs.DirEntry) error {
+		t.Errorf("`%s` should not be visited", path)
+		return nil
+	})
+	assert.Equal(t, ErrNegativeGlob, err)
+	paths, err := GlobFS(testFS, glob)
+	assert.Equal(t, ErrNegativeGlob, err)
+	assert.Nil(t, paths)
+}
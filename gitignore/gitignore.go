@@ -0,0 +1,292 @@
+// Package gitignore matches paths against the patterns of a .gitignore file, with git's semantics.
+//
+// Each line of the file is translated to an ohmyglob pattern: a pattern without a slash (other than a trailing one)
+// matches at any depth, a leading slash anchors a pattern to the directory containing the file, a trailing slash
+// restricts a pattern to directories, and later patterns take precedence over earlier ones. As in git, a path can't be
+// re-included by a negated pattern if any of its parent directories is excluded.
+//
+// A Hierarchy applies the ignore files found throughout an fs.FS, each to the directory containing it.
+package gitignore
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"unicode"
+
+	glob "github.com/obeattie/ohmyglob"
+)
+
+// Rule is a single pattern from a .gitignore file
+type Rule struct {
+	// The pattern, as written in the file (without any trailing spaces)
+	Pattern string
+	// The line of the file on which the pattern appears, starting at 1
+	Line int
+	// The path of the file containing the pattern, if it was loaded by a Hierarchy
+	File string
+	// Set to true if the pattern re-includes the paths it matches (ie. it begins with !)
+	Negated bool
+	// Set to true if the pattern only matches directories (ie. it ends with /)
+	DirOnly bool
+	// Set to true if the pattern matches relative to the directory containing the file, rather than at any depth (ie.
+	// it contains a / other than at its end)
+	Anchored bool
+	// The translated pattern, which matches the paths the rule applies to (without regard to Negated or DirOnly)
+	Glob glob.Glob
+}
+
+// Matcher decides whether paths are ignored by the rules of a .gitignore file. Paths are separated by slashes, and are
+// relative to the directory containing the file. A Matcher is immutable.
+type Matcher interface {
+	// Match reports whether the path is ignored. isDir reports whether the path is a directory, as patterns ending
+	// with a slash only match directories.
+	Match(path string, isDir bool) bool
+	// MatchingRule returns the rule which decides whether the path is ignored (the path is ignored if the rule is not
+	// Negated), or nil if no rule applies to it. If a parent directory of the path is ignored, the rule which ignores
+	// it is returned.
+	MatchingRule(path string, isDir bool) *Rule
+	// Rules returns the rules of the file, in order
+	Rules() []*Rule
+}
+
+type matcher struct {
+	rules []*Rule
+	// Map the globs of all the rules, and of those which are not DirOnly, to their rules
+	dirs  glob.GlobMap[*Rule]
+	files glob.GlobMap[*Rule]
+}
+
+// Parse reads the contents of a .gitignore file, and constructs a Matcher from its rules
+func Parse(r io.Reader) (Matcher, error) {
+	lines, err := readLines(r)
+	if err != nil {
+		return nil, err
+	}
+	return Compile(lines)
+}
+
+func readLines(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines, scanner.Err()
+}
+
+// Compile constructs a Matcher from the lines of a .gitignore file. Blank lines and comments are skipped, as are
+// patterns which git considers invalid (eg. those ending with an unescaped backslash, or with an unterminated bracket
+// expression), which never match anything.
+func Compile(lines []string) (Matcher, error) {
+	return compile(lines, "")
+}
+
+func compile(lines []string, file string) (*matcher, error) {
+	m := &matcher{}
+	var dirGlobs, fileGlobs []glob.Glob
+	var dirRules, fileRules []*Rule
+	for i, line := range lines {
+		rule := ParseRule(line)
+		if rule == nil {
+			continue
+		}
+		rule.Line = i + 1
+		rule.File = file
+		m.rules = append(m.rules, rule)
+		dirGlobs, dirRules = append(dirGlobs, rule.Glob), append(dirRules, rule)
+		if !rule.DirOnly {
+			fileGlobs, fileRules = append(fileGlobs, rule.Glob), append(fileRules, rule)
+		}
+	}
+
+	var err error
+	if m.dirs, err = glob.NewGlobMap(dirGlobs, dirRules); err != nil {
+		return nil, err
+	}
+	if m.files, err = glob.NewGlobMap(fileGlobs, fileRules); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ParseRule parses a single line of a .gitignore file, returning nil if it is blank, a comment, or an invalid pattern.
+// The Line of the returned rule is 0.
+func ParseRule(line string) *Rule {
+	pattern := trimTrailingSpaces(line)
+	if pattern == "" || strings.HasPrefix(pattern, "#") {
+		return nil
+	}
+
+	rule := &Rule{Pattern: pattern}
+	body := pattern
+	if strings.HasPrefix(body, "!") {
+		rule.Negated = true
+		body = body[1:]
+	}
+	if strings.HasSuffix(body, "/") {
+		rule.DirOnly = true
+		body = strings.TrimSuffix(body, "/")
+	}
+	rule.Anchored = strings.Contains(body, "/")
+	body = strings.TrimPrefix(body, "/")
+	if body == "" {
+		return nil
+	}
+
+	translated, ok := translate(body, rule.Anchored)
+	if !ok {
+		return nil
+	}
+	g, err := glob.Compile(translated, glob.DefaultOptions)
+	if err != nil {
+		return nil
+	}
+	rule.Glob = g
+	return rule
+}
+
+// Removes trailing spaces from the line, except those escaped with a backslash
+func trimTrailingSpaces(line string) string {
+	end := 0
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case ' ':
+			continue
+		case '\\':
+			if i+1 < len(line) {
+				i++
+			}
+		}
+		end = i + 1
+	}
+	return line[:end]
+}
+
+// translate converts the body of a gitignore pattern (without any negation prefix, trailing slash or leading slash) to
+// the equivalent ohmyglob pattern, returning false if the pattern is invalid
+func translate(body string, anchored bool) (string, bool) {
+	buf := new(strings.Builder)
+	if !anchored {
+		buf.WriteString("**/")
+	}
+
+	runes := []rune(body)
+	for i := 0; i < len(runes); i++ {
+		switch r := runes[i]; r {
+		case '\\':
+			if i+1 == len(runes) {
+				return "", false
+			}
+			i++
+			writeLiteral(buf, runes[i])
+		case '*':
+			start := i
+			for i+1 < len(runes) && runes[i+1] == '*' {
+				i++
+			}
+			// A run of stars is only a globstar if it is a whole segment; otherwise it is a single star
+			wholeSegment := (start == 0 || runes[start-1] == '/') && (i+1 == len(runes) || runes[i+1] == '/')
+			switch {
+			case i == start || !wholeSegment:
+				buf.WriteRune('*')
+			case i+1 == len(runes):
+				// A trailing globstar matches everything inside the directory, but not the directory itself
+				buf.WriteString("**/*")
+			default:
+				buf.WriteString("**")
+			}
+		case '[':
+			end := bracketEnd(runes, i)
+			if end < 0 {
+				return "", false
+			}
+			buf.WriteString(string(runes[i : end+1]))
+			i = end
+		case '?', '/':
+			buf.WriteRune(r)
+		default:
+			writeLiteral(buf, r)
+		}
+	}
+	return buf.String(), true
+}
+
+// Writes a rune which is to be matched literally. Whitespace is written as a bracket expression, so that it isn't
+// trimmed from the ends of the pattern.
+func writeLiteral(buf *strings.Builder, r rune) {
+	switch {
+	case unicode.IsSpace(r):
+		buf.WriteRune('[')
+		buf.WriteRune(r)
+		buf.WriteRune(']')
+	case strings.ContainsRune(`\*?[]{},!`, r):
+		buf.WriteRune(glob.Escaper)
+		buf.WriteRune(r)
+	default:
+		buf.WriteRune(r)
+	}
+}
+
+// Returns the index of the ] closing the bracket expression opened at start, or -1 if it is not closed
+func bracketEnd(runes []rune, start int) int {
+	i := start + 1
+	if i < len(runes) && (runes[i] == '!' || runes[i] == '^') {
+		i++
+	}
+	// A ] which is the first member is literal
+	if i < len(runes) && runes[i] == ']' {
+		i++
+	}
+	for ; i < len(runes); i++ {
+		switch runes[i] {
+		case '\\':
+			i++
+		case '[':
+			// A named class (eg. [:alpha:]) may contain a ]
+			if i+1 < len(runes) && runes[i+1] == ':' {
+				for j := i + 2; j+1 < len(runes); j++ {
+					if runes[j] == ':' && runes[j+1] == ']' {
+						i = j + 1
+						break
+					}
+				}
+			}
+		case ']':
+			return i
+		}
+	}
+	return -1
+}
+
+// Returns the rule that decides whether the path itself is ignored, without regard to its parent directories
+func (m *matcher) rule(path string, isDir bool) *Rule {
+	rules := m.files
+	if isDir {
+		rules = m.dirs
+	}
+	rule, _ := rules.Get(path)
+	return rule
+}
+
+func (m *matcher) MatchingRule(path string, isDir bool) *Rule {
+	path = strings.Trim(path, "/")
+	// Git doesn't descend into ignored directories, so nothing beneath one can be re-included
+	for i := 0; i < len(path); i++ {
+		if path[i] == '/' {
+			if rule := m.rule(path[:i], true); rule != nil && !rule.Negated {
+				return rule
+			}
+		}
+	}
+	return m.rule(path, isDir)
+}
+
+func (m *matcher) Match(path string, isDir bool) bool {
+	rule := m.MatchingRule(path, isDir)
+	return rule != nil && !rule.Negated
+}
+
+func (m *matcher) Rules() []*Rule {
+	return append([]*Rule(nil), m.rules...)
+}
@@ -0,0 +1,222 @@
+package gitignore
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type conformanceCase struct {
+	path    string
+	isDir   bool
+	ignored bool
+}
+
+// The examples given in git's documentation (gitignore(5)), along with the results git gives for them
+var conformance = []struct {
+	lines []string
+	cases []conformanceCase
+}{
+	// "hello.*" matches any file or directory whose name begins with "hello."
+	{[]string{`hello.*`}, []conformanceCase{
+		{`hello.c`, false, true},
+		{`hello.txt`, false, true},
+		{`a/hello.java`, false, true},
+		{`a/hello.d`, true, true},
+		{`hello`, false, false},
+		{`a/ohello.c`, false, false},
+	}},
+	// "/hello.*" only matches in the directory containing the file
+	{[]string{`/hello.*`}, []conformanceCase{
+		{`hello.txt`, false, true},
+		{`hello.c`, false, true},
+		{`a/hello.java`, false, false},
+	}},
+	// "doc/frotz/" matches the directory "doc/frotz", but not "a/doc/frotz"
+	{[]string{`doc/frotz/`}, []conformanceCase{
+		{`doc/frotz`, true, true},
+		{`doc/frotz/file.txt`, false, true},
+		{`doc/frotz`, false, false},
+		{`a/doc/frotz`, true, false},
+	}},
+	// "frotz/" matches "frotz" and "a/frotz", if they are directories
+	{[]string{`frotz/`}, []conformanceCase{
+		{`frotz`, true, true},
+		{`a/frotz`, true, true},
+		{`a/frotz/b/c.txt`, false, true},
+		{`frotz`, false, false},
+		{`a/frotz`, false, false},
+	}},
+	// "foo/*" matches "foo/test.json" and the directory "foo/bar" (and so, because the directory is ignored, the files
+	// within it)
+	{[]string{`foo/*`}, []conformanceCase{
+		{`foo/test.json`, false, true},
+		{`foo/bar`, true, true},
+		{`foo/bar/hello.c`, false, true},
+		{`foo`, true, false},
+		{`a/foo/test.json`, false, false},
+	}},
+	// "Documentation/*.html" doesn't match in subdirectories, or in other directories of the same name
+	{[]string{`Documentation/*.html`}, []conformanceCase{
+		{`Documentation/git.html`, false, true},
+		{`Documentation/ppc/ppc.html`, false, false},
+		{`tools/perf/Documentation/perf.html`, false, false},
+	}},
+	// "/*.c" matches "cat-file.c", but not "mozilla-sha1/sha1.c"
+	{[]string{`/*.c`}, []conformanceCase{
+		{`cat-file.c`, false, true},
+		{`mozilla-sha1/sha1.c`, false, false},
+	}},
+	// "**/foo" matches "foo" anywhere; "**/foo/bar" matches "bar" anywhere directly under "foo"
+	{[]string{`**/foo`, `**/foo/bar`}, []conformanceCase{
+		{`foo`, false, true},
+		{`a/b/foo`, true, true},
+		{`x/foo/bar`, false, true},
+		{`x/food`, false, false},
+	}},
+	{[]string{`**/foo/bar`}, []conformanceCase{
+		{`foo/bar`, false, true},
+		{`x/y/foo/bar`, true, true},
+		{`foo/x/bar`, false, false},
+	}},
+	// "abc/**" matches everything inside "abc", but not "abc" itself
+	{[]string{`abc/**`}, []conformanceCase{
+		{`abc/x`, false, true},
+		{`abc/x/y/z.txt`, false, true},
+		{`abc`, true, false},
+		{`x/abc/y`, false, false},
+	}},
+	// "a/**/b" matches "a/b", "a/x/b", "a/x/y/b" and so on
+	{[]string{`a/**/b`}, []conformanceCase{
+		{`a/b`, false, true},
+		{`a/x/b`, false, true},
+		{`a/x/y/b`, false, true},
+		{`a/x/c`, false, false},
+	}},
+	// Other consecutive asterisks are regular asterisks
+	{[]string{`foo**bar`, `x/**y`}, []conformanceCase{
+		{`fooqbar`, false, true},
+		{`foo/bar`, false, false},
+		{`x/ay`, false, true},
+		{`x/a/y`, false, false},
+	}},
+	// Exclude everything except the directory "foo/bar"
+	{[]string{`/*`, `!/foo`, `/foo/*`, `!/foo/bar`}, []conformanceCase{
+		{`README`, false, true},
+		{`src`, true, true},
+		{`src/main.c`, false, true},
+		{`foo`, true, false},
+		{`foo/x.txt`, false, true},
+		{`foo/baz`, true, true},
+		{`foo/bar`, true, false},
+		{`foo/bar/x.txt`, false, false},
+	}},
+	// It is not possible to re-include a file if a parent directory of that file is excluded
+	{[]string{`build/`, `!build/keep.txt`, `!build/sub/`}, []conformanceCase{
+		{`build/keep.txt`, false, true},
+		{`build/sub`, true, true},
+		{`build/sub/x`, false, true},
+	}},
+	// A negated pattern re-includes a file excluded by an earlier pattern, and the last matching pattern wins
+	{[]string{`*.log`, `!important.log`, `important.log.*`}, []conformanceCase{
+		{`debug.log`, false, true},
+		{`important.log`, false, false},
+		{`logs/important.log`, false, false},
+		{`important.log.1`, false, true},
+	}},
+	// Comments, escaped hashes and exclamation marks, and trailing spaces
+	{[]string{`# comment`, ``, `\#hash`, `\!important!.txt`, `trailing   `, `escaped\ `, `   leading`}, []conformanceCase{
+		{`# comment`, false, false},
+		{`#hash`, false, true},
+		{`!important!.txt`, false, true},
+		{`trailing`, false, true},
+		{`trailing `, false, false},
+		{`escaped `, false, true},
+		{`escaped`, false, false},
+		{`   leading`, false, true},
+		{`leading`, false, false},
+	}},
+	// Characters which are special to ohmyglob, but not to git, are literal
+	{[]string{`{a,b}.txt`, `c,d`}, []conformanceCase{
+		{`{a,b}.txt`, false, true},
+		{`a.txt`, false, false},
+		{`x/c,d`, false, true},
+	}},
+	// Bracket expressions, including named classes and literal closing brackets
+	{[]string{`file[0-9].txt`, `[!a-z]*.md`, `x[[:digit:]]`, `y[]]`}, []conformanceCase{
+		{`file1.txt`, false, true},
+		{`filex.txt`, false, false},
+		{`README.md`, false, true},
+		{`readme.md`, false, false},
+		{`x5`, false, true},
+		{`xa`, false, false},
+		{`y]`, false, true},
+	}},
+	// Invalid patterns never match
+	{[]string{`foo\`, `[abc`, `/`}, []conformanceCase{
+		{`foo\`, false, false},
+		{`foo`, false, false},
+		{`[abc`, false, false},
+		{`a`, false, false},
+	}},
+}
+
+func TestConformance(t *testing.T) {
+	for _, c := range conformance {
+		m, err := Compile(c.lines)
+		if !assert.NoError(t, err) {
+			continue
+		}
+		for _, cc := range c.cases {
+			assert.Equal(t, cc.ignored, m.Match(cc.path, cc.isDir), "Unexpected result for `%s` (dir: %v) with %q",
+				cc.path, cc.isDir, c.lines)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	m, err := Parse(strings.NewReader("# build output\n/build/\n*.o\r\n\n!keep.o\n"))
+	assert.NoError(t, err)
+
+	rules := m.Rules()
+	if assert.Len(t, rules, 3) {
+		assert.Equal(t, `/build/`, rules[0].Pattern)
+		assert.Equal(t, 2, rules[0].Line)
+		assert.True(t, rules[0].DirOnly)
+		assert.True(t, rules[0].Anchored)
+		assert.False(t, rules[0].Negated)
+		assert.Equal(t, `*.o`, rules[1].Pattern)
+		assert.Equal(t, 3, rules[1].Line)
+		assert.False(t, rules[1].Anchored)
+		assert.Equal(t, `!keep.o`, rules[2].Pattern)
+		assert.Equal(t, 5, rules[2].Line)
+		assert.True(t, rules[2].Negated)
+	}
+
+	assert.Equal(t, rules[1], m.MatchingRule(`src/x.o`, false))
+	assert.Equal(t, rules[2], m.MatchingRule(`src/keep.o`, false))
+	assert.Equal(t, rules[0], m.MatchingRule(`build/keep.o`, false))
+	assert.Nil(t, m.MatchingRule(`src/x.c`, false))
+}
+
+func TestParseRule(t *testing.T) {
+	assert.Nil(t, ParseRule(``))
+	assert.Nil(t, ParseRule(`   `))
+	assert.Nil(t, ParseRule(`# comment`))
+	assert.Nil(t, ParseRule(`!`))
+
+	rule := ParseRule(`!/docs/**/*.md/`)
+	if assert.NotNil(t, rule) {
+		assert.True(t, rule.Negated)
+		assert.True(t, rule.DirOnly)
+		assert.True(t, rule.Anchored)
+		assert.Equal(t, `docs/**/*.md`, rule.Glob.String())
+	}
+
+	rule = ParseRule(`node_modules/`)
+	if assert.NotNil(t, rule) {
+		assert.False(t, rule.Anchored)
+		assert.Equal(t, `**/node_modules`, rule.Glob.String())
+	}
+}
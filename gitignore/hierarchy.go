@@ -0,0 +1,150 @@
+package gitignore
+
+import (
+	"errors"
+	"io/fs"
+	"path"
+	"strings"
+	"sync"
+)
+
+// Hierarchy decides whether the paths in an fs.FS are ignored by the ignore files within it. Each ignore file applies
+// to the directory containing it (its patterns are matched against paths relative to that directory), and the rules
+// of deeper files take precedence over those of shallower ones. As with a single file, a path can't be re-included if
+// any of its parent directories is ignored.
+//
+// Ignore files are loaded as they are first needed, and are cached; a Hierarchy is safe for concurrent use, but does
+// not notice changes to files it has already loaded.
+type Hierarchy interface {
+	// Match reports whether the path (which is slash-separated and relative to the root of the fs.FS, as with any path
+	// in an fs.FS) is ignored. isDir reports whether the path is a directory. An error is returned if an ignore file
+	// can't be read.
+	Match(path string, isDir bool) (bool, error)
+	// MatchingRule returns the rule which decides whether the path is ignored (see Matcher.MatchingRule), or nil if no
+	// rule applies to it. The rule's File identifies the ignore file it was loaded from.
+	MatchingRule(path string, isDir bool) (*Rule, error)
+	// Walk calls fn for each path in the fs.FS which is not ignored, in lexical order within each directory, skipping
+	// ignored directories entirely. The root "." itself is never passed to fn. If fn returns fs.SkipDir for a
+	// directory, the directory is skipped; if it returns fs.SkipAll, walking stops without error; any other error stops
+	// walking and is returned.
+	Walk(fn func(path string, d fs.DirEntry) error) error
+}
+
+type hierarchy struct {
+	fsys     fs.FS
+	filename string
+	mu       sync.Mutex
+	// The matcher for the ignore file in each directory that has been loaded (nil if the directory has no ignore file)
+	matchers map[string]*matcher
+}
+
+// NewHierarchy returns a Hierarchy which loads the ignore files with the given name (eg. ".gitignore", or any other
+// file using the same syntax) from fsys
+func NewHierarchy(fsys fs.FS, filename string) Hierarchy {
+	return &hierarchy{
+		fsys:     fsys,
+		filename: filename,
+		matchers: make(map[string]*matcher),
+	}
+}
+
+// Returns the matcher for the ignore file in the directory, loading it if it hasn't been already
+func (h *hierarchy) load(dir string) (*matcher, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if m, ok := h.matchers[dir]; ok {
+		return m, nil
+	}
+
+	file := path.Join(dir, h.filename)
+	f, err := h.fsys.Open(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		h.matchers[dir] = nil
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	lines, err := readLines(f)
+	if err != nil {
+		return nil, err
+	}
+	m, err := compile(lines, file)
+	if err != nil {
+		return nil, err
+	}
+	h.matchers[dir] = m
+	return m, nil
+}
+
+// Returns the rule that decides whether the path itself is ignored, without regard to its parent directories: that of
+// the deepest ignore file with a rule matching the path
+func (h *hierarchy) rule(p string, isDir bool) (*Rule, error) {
+	dir := p
+	for dir != "." {
+		dir = path.Dir(dir)
+		m, err := h.load(dir)
+		if err != nil {
+			return nil, err
+		} else if m == nil {
+			continue
+		}
+
+		rel := p
+		if dir != "." {
+			rel = strings.TrimPrefix(p, dir+"/")
+		}
+		if rule := m.rule(rel, isDir); rule != nil {
+			return rule, nil
+		}
+	}
+	return nil, nil
+}
+
+func (h *hierarchy) MatchingRule(p string, isDir bool) (*Rule, error) {
+	p = path.Clean(p)
+	// Nothing beneath an ignored directory can be re-included
+	for i := 0; i < len(p); i++ {
+		if p[i] == '/' {
+			rule, err := h.rule(p[:i], true)
+			if err != nil {
+				return nil, err
+			} else if rule != nil && !rule.Negated {
+				return rule, nil
+			}
+		}
+	}
+	return h.rule(p, isDir)
+}
+
+func (h *hierarchy) Match(p string, isDir bool) (bool, error) {
+	rule, err := h.MatchingRule(p, isDir)
+	return rule != nil && !rule.Negated, err
+}
+
+func (h *hierarchy) Walk(fn func(path string, d fs.DirEntry) error) error {
+	err := fs.WalkDir(h.fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		} else if p == "." {
+			return nil
+		}
+
+		// Ignored directories are skipped, so the parent directories of the path are known not to be ignored
+		rule, err := h.rule(p, d.IsDir())
+		if err != nil {
+			return err
+		} else if rule != nil && !rule.Negated {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		return fn(p, d)
+	})
+	if err == fs.SkipAll {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,170 @@
+package gitignore
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var hierarchyFS = fstest.MapFS{
+	".gitignore":               {Data: []byte("*.log\n/build/\ntmp/\n")},
+	"main.go":                  {},
+	"debug.log":                {},
+	"build/out":                {},
+	"src/.gitignore":           {Data: []byte("# keep logs in src\n!*.log\n/generated.go\n")},
+	"src/main.go":              {},
+	"src/app.log":              {},
+	"src/generated.go":         {},
+	"src/build/out":            {},
+	"src/pkg/generated.go":     {},
+	"src/pkg/tmp/x":            {},
+	"src/pkg/.gitignore":       {Data: []byte("*.log\n")},
+	"src/pkg/pkg.log":          {},
+	"docs/.gitignore":          {Data: []byte("!/tmp/\n")},
+	"docs/tmp/notes.md":        {},
+	"vendor/.gitignore":        {Data: []byte("*\n!.gitignore\n")},
+	"vendor/lib/lib.go":        {},
+	"vendor/lib/.gitignore":    {Data: []byte("!lib.go\n")},
+	"third_party/tmp/x/y.txt":  {},
+	"third_party/tmp/x/y.keep": {},
+}
+
+func TestHierarchy_MatchingRule(t *testing.T) {
+	h := NewHierarchy(hierarchyFS, ".gitignore")
+	cases := []struct {
+		path    string
+		isDir   bool
+		file    string
+		line    int
+		ignored bool
+	}{
+		{`main.go`, false, ``, 0, false},
+		{`debug.log`, false, `.gitignore`, 1, true},
+		{`build`, true, `.gitignore`, 2, true},
+		{`build/out`, false, `.gitignore`, 2, true},
+		// Deeper files take precedence, and their patterns are relative to their own directory
+		{`src/app.log`, false, `src/.gitignore`, 2, false},
+		{`src/generated.go`, false, `src/.gitignore`, 3, true},
+		{`src/pkg/generated.go`, false, ``, 0, false},
+		{`src/build`, true, ``, 0, false},
+		{`src/pkg/pkg.log`, false, `src/pkg/.gitignore`, 1, true},
+		{`src/pkg/tmp/x`, false, `.gitignore`, 3, true},
+		{`docs/tmp`, true, `docs/.gitignore`, 1, false},
+		{`docs/tmp/notes.md`, false, ``, 0, false},
+		// Nothing beneath an ignored directory can be re-included, even by a deeper file
+		{`vendor/.gitignore`, false, `vendor/.gitignore`, 2, false},
+		{`vendor/lib`, true, `vendor/.gitignore`, 1, true},
+		{`vendor/lib/lib.go`, false, `vendor/.gitignore`, 1, true},
+	}
+	for _, c := range cases {
+		rule, err := h.MatchingRule(c.path, c.isDir)
+		if !assert.NoError(t, err) {
+			continue
+		}
+		if c.file == `` {
+			assert.Nil(t, rule, "No rule should apply to `%s`", c.path)
+		} else if assert.NotNil(t, rule, "A rule should apply to `%s`", c.path) {
+			assert.Equal(t, c.file, rule.File, "Unexpected file for `%s`", c.path)
+			assert.Equal(t, c.line, rule.Line, "Unexpected line for `%s`", c.path)
+		}
+
+		ignored, err := h.Match(c.path, c.isDir)
+		assert.NoError(t, err)
+		assert.Equal(t, c.ignored, ignored, "Unexpected result for `%s`", c.path)
+	}
+}
+
+func TestHierarchy_Walk(t *testing.T) {
+	recorder := &openRecorder{FS: hierarchyFS}
+	h := NewHierarchy(recorder, ".gitignore")
+	var paths []string
+	err := h.Walk(func(path string, d fs.DirEntry) error {
+		if !d.IsDir() {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		`.gitignore`,
+		`docs/.gitignore`,
+		`docs/tmp/notes.md`,
+		`main.go`,
+		`src/.gitignore`,
+		`src/app.log`,
+		`src/build/out`,
+		`src/main.go`,
+		`src/pkg/.gitignore`,
+		`src/pkg/generated.go`,
+		`vendor/.gitignore`,
+	}, paths)
+
+	// Ignored directories are never read, so neither are the ignore files within them
+	assert.NotContains(t, recorder.opened, `build`)
+	assert.NotContains(t, recorder.opened, `third_party/tmp`)
+	assert.NotContains(t, recorder.opened, `vendor/lib`)
+	assert.NotContains(t, recorder.opened, `vendor/lib/.gitignore`)
+	assert.Contains(t, recorder.opened, `src/pkg/.gitignore`)
+}
+
+func TestHierarchy_Walk_Stop(t *testing.T) {
+	h := NewHierarchy(hierarchyFS, ".gitignore")
+	var paths []string
+	err := h.Walk(func(path string, d fs.DirEntry) error {
+		paths = append(paths, path)
+		if path == `docs` {
+			return fs.SkipDir
+		} else if path == `main.go` {
+			return fs.SkipAll
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{`.gitignore`, `docs`, `main.go`}, paths)
+
+	fail := errors.New("fail")
+	err = h.Walk(func(path string, d fs.DirEntry) error {
+		return fail
+	})
+	assert.Equal(t, fail, err)
+}
+
+// errorFS fails to open the named file
+type errorFS struct {
+	fs.FS
+	name string
+}
+
+func (e errorFS) Open(name string) (fs.File, error) {
+	if name == e.name {
+		return nil, fs.ErrPermission
+	}
+	return e.FS.Open(name)
+}
+
+// openRecorder records the paths opened in the wrapped filesystem
+type openRecorder struct {
+	fs.FS
+	opened []string
+}
+
+func (r *openRecorder) Open(name string) (fs.File, error) {
+	r.opened = append(r.opened, name)
+	return r.FS.Open(name)
+}
+
+func TestHierarchy_Error(t *testing.T) {
+	h := NewHierarchy(errorFS{hierarchyFS, `src/.gitignore`}, ".gitignore")
+	_, err := h.Match(`src/main.go`, false)
+	assert.True(t, errors.Is(err, fs.ErrPermission))
+	ignored, err := h.Match(`docs/tmp`, true)
+	assert.NoError(t, err)
+	assert.False(t, ignored)
+	err = h.Walk(func(path string, d fs.DirEntry) error {
+		return nil
+	})
+	assert.True(t, errors.Is(err, fs.ErrPermission))
+}
@@ -3,7 +3,6 @@ package ohmyglob
 
 import (
 	"bytes"
-	"fmt"
 	"io"
 	"os"
 	"regexp"
@@ -18,8 +17,9 @@ var (
 	Logger log.LoggerInterface
 	// Escaper is the character used to escape a meaningful character
 	Escaper = '\\'
-	// Runes that, in addition to the separator, mean something when they appear in the glob (includes Escaper)
-	expanders = []rune{'?', '*', '!', Escaper}
+	// Runes that, in addition to the separator and the Escaper, mean something when they appear in the glob (commas
+	// also do, within brace expressions, unless they are separators; see specialRunes)
+	expanders = []rune{'?', '*', '!', '[', ']', '{', '}'}
 )
 
 func init() {
@@ -33,15 +33,40 @@ func init() {
 }
 
 type processedToken struct {
-	contents  *bytes.Buffer
+	// The regular expression component the token was converted to
+	contents *bytes.Buffer
+	// The token, as yielded by the tokeniser
+	token     string
 	tokenType tc
+	// The byte offset of the token within the pattern
+	offset int
+	// The byte offsets of any globstars which were merged into this one
+	mergedOffsets []int
+	// Set to true if the token is within a negated extended glob group (which is not part of the regular expression)
+	negatedGroup bool
 }
 
 type parserState struct {
 	options *Options
-	// The regex-escaped separator character
+	// A regular expression component matching either separator character
 	escapedSeparator string
-	processedTokens  []processedToken
+	// The regex-escaped characters which wildcards never match, for use within a negated class
+	excludedRunes   string
+	processedTokens []processedToken
+	// Whether the globstar currently being processed is the last token in its alternative
+	globStarIsLast bool
+	// Whether a globstar in the pattern adjoins a brace token, or the expansions of its brace expressions glue stars
+	// into a globstar, so the pattern may match differently in each of its expansions (see newExpandedGlob)
+	bracedGlobStar bool
+	// The opening tokens of the brace expressions and extended glob groups that are currently open
+	groupStack []string
+	// The names of the named captures in the pattern
+	captureNames map[string]bool
+	// The number of negated extended glob groups in the pattern, and whether one is currently open
+	negatedGroups  int
+	inNegatedGroup bool
+	// The byte offset within the pattern of the token currently being processed
+	tokenOffset int
 }
 
 // GlobMatcher is the basic interface of a Glob or GlobSet. It provides a Regexp-style interface for checking matches.
@@ -61,6 +86,32 @@ type Glob interface {
 	String() string
 	// IsNegative returns whether the pattern was negated (prefixed with !)
 	IsNegative() bool
+	// FindStringSubmatch returns a slice holding the text of the match and, in pattern order, the text matched by each
+	// wildcard (*, **, ?) and bracket expression in the pattern, or nil if there is no match. A globstar's text excludes
+	// its surrounding separators. Wildcards which took no part in the match (eg. those in an unused brace alternative,
+	// or a globstar which matched no segments) have empty text. Wildcards within negated extended glob groups are not
+	// captured.
+	FindStringSubmatch(s string) []string
+	// FindSubmatch is like FindStringSubmatch, but matches the byte slice b
+	FindSubmatch(b []byte) [][]byte
+	// FindStringSubmatchIndex is like FindStringSubmatch, but returns pairs of indices identifying the text; the indices
+	// of wildcards which took no part in the match are -1
+	FindStringSubmatchIndex(s string) []int
+	// FindSubmatchIndex is like FindStringSubmatchIndex, but matches the byte slice b
+	FindSubmatchIndex(b []byte) []int
+	// Captures returns the text matched by each named capture (eg. "{svc:*}") in the pattern, by name, or nil if there
+	// is no match
+	Captures(s string) map[string]string
+	// CouldMatchPrefix reports whether the Glob could match any path beneath the directory dir (ie. any string
+	// beginning with dir followed by the separator). It may report true even if no such path matches, but never
+	// reports false if one does, so it can be used to skip directories when walking a tree.
+	CouldMatchPrefix(dir string) bool
+	// Specificity returns a score of how specific the pattern is, which is used to decide which Glob takes precedence
+	// in a set with MostSpecific precedence. Higher scores are more specific: the score compares, in order, the number
+	// of literal characters (more is more specific), the number of globstars (fewer), and the number of separators in
+	// the literal prefix of a pattern anchored at the start (more). Literal characters within brace expressions and
+	// extended glob groups are not counted.
+	Specificity() int64
 }
 
 // Glob is a glob pattern that has been compiled into a regular expression.
@@ -73,18 +124,59 @@ type globImpl struct {
 	parserState *parserState
 	// Set to true if the pattern was negated
 	negated bool
+	// Literal text which appears in all input the Glob matches (see findRequiredLiteral)
+	literal string
+	// The specificity score of the pattern (see computeSpecificity)
+	specificity int64
+	// The directory beneath which all matched paths lie (see findWalkRoot)
+	root string
+	// The options the pattern was compiled with
+	options *Options
+	// Answers CouldMatchPrefix queries
+	prefix *prefixMatcher
 }
 
 // Options modify the behaviour of Glob parsing
 type Options struct {
 	// The character used to split path components
 	Separator rune
+	// An additional character which also splits path components, or 0 for none (eg. '/' alongside a Separator of '\\').
+	// A separator in a pattern matches either character in the input.
+	AltSeparator rune
+	// The character used to escape meaningful characters in patterns, or 0 to use the package-level Escaper
+	Escaper rune
 	// Set to false to allow any prefix before the glob match
 	MatchAtStart bool
 	// Set to false to allow any suffix after the glob match
 	MatchAtEnd bool
+	// How letter case is treated when matching literals and character classes
+	CaseFolding CaseFolding
+	// Set to true to enable ksh/bash-style extended glob groups: ?(a|b) matches zero or one of the alternatives, *(a|b)
+	// zero or more, +(a|b) one or more, @(a|b) exactly one, and !(a|b) anything except one of the alternatives
+	ExtGlob bool
+	// Set to true to reject patterns which are likely to be mistakes, rather than guessing at their meaning: those with
+	// leading or trailing whitespace, a dangling Escaper at the end, an unclosed brace or bracket, a run of three or
+	// more stars, or a globstar which is not a whole path segment (eg. "foo**")
+	Strict bool
+	// Set to true to treat a drive letter (eg. "C:") or UNC prefix (eg. "\\server\share") at the start of the input
+	// as its root, which only patterns beginning with a root can match: wildcards never match a colon (which can't
+	// otherwise appear in a Windows path), and globstars never match text beginning with a separator
+	VolumeRoots bool
 }
 
+// CaseFolding determines how letter case is treated when matching
+type CaseFolding uint8
+
+const (
+	// CaseSensitive matches letters exactly
+	CaseSensitive = CaseFolding(0x0)
+	// FoldASCII matches the ASCII letters A-Z case-insensitively; all other characters are matched exactly
+	FoldASCII = CaseFolding(0x1)
+	// FoldUnicode matches all characters case-insensitively, using Unicode simple case folding (so, for example, "k"
+	// also matches the Kelvin sign "K")
+	FoldUnicode = CaseFolding(0x2)
+)
+
 // DefaultOptions are a default set of Options that uses a forward slash as a separator, and require a full match
 var DefaultOptions = &Options{
 	Separator:    '/',
@@ -92,6 +184,47 @@ var DefaultOptions = &Options{
 	MatchAtEnd:   true,
 }
 
+// WindowsOptions are a set of Options for Windows paths, which require a full match. Both backslashes and forward
+// slashes are separators, so a backtick is the Escaper (as in PowerShell); drive letters and UNC prefixes are roots
+// (see VolumeRoots), and case is folded.
+var WindowsOptions = &Options{
+	Separator:    '\\',
+	AltSeparator: '/',
+	Escaper:      '`',
+	MatchAtStart: true,
+	MatchAtEnd:   true,
+	CaseFolding:  FoldUnicode,
+	VolumeRoots:  true,
+}
+
+// escaper returns the character used to escape meaningful characters in patterns
+func (o *Options) escaper() rune {
+	if o.Escaper != 0 {
+		return o.Escaper
+	}
+	return Escaper
+}
+
+// altSeparator returns the additional separator character, or the Separator if there is none
+func (o *Options) altSeparator() rune {
+	if o.AltSeparator != 0 {
+		return o.AltSeparator
+	}
+	return o.Separator
+}
+
+// excludedRunes returns the runes which wildcards never match: the separators and, with VolumeRoots, the colon
+func (o *Options) excludedRunes() []rune {
+	runes := []rune{o.Separator}
+	if o.AltSeparator != 0 && o.AltSeparator != o.Separator {
+		runes = append(runes, o.AltSeparator)
+	}
+	if o.VolumeRoots {
+		runes = append(runes, ':')
+	}
+	return runes
+}
+
 func (g *globImpl) String() string {
 	return g.globPattern
 }
@@ -100,6 +233,52 @@ func (g *globImpl) IsNegative() bool {
 	return g.negated
 }
 
+func (g *globImpl) Captures(s string) map[string]string {
+	return namedCaptures(g.SubexpNames(), g.FindStringSubmatch(s))
+}
+
+// namedCaptures returns the named submatches of a match, by name, given the names of the groups
+func namedCaptures(names []string, submatches []string) map[string]string {
+	if submatches == nil {
+		return nil
+	}
+	captures := make(map[string]string)
+	for i, name := range names {
+		if name != "" {
+			captures[name] = submatches[i]
+		}
+	}
+	return captures
+}
+
+// submatchStrings returns the text of the submatches at the pairs of indices (as returned by FindStringSubmatchIndex)
+func submatchStrings(s string, loc []int) []string {
+	if loc == nil {
+		return nil
+	}
+	result := make([]string, len(loc)/2)
+	for i := range result {
+		if loc[2*i] >= 0 {
+			result[i] = s[loc[2*i]:loc[2*i+1]]
+		}
+	}
+	return result
+}
+
+// submatchBytes returns the submatches of b at the pairs of indices (as returned by FindSubmatchIndex)
+func submatchBytes(b []byte, loc []int) [][]byte {
+	if loc == nil {
+		return nil
+	}
+	result := make([][]byte, len(loc)/2)
+	for i := range result {
+		if loc[2*i] >= 0 {
+			result[i] = b[loc[2*i]:loc[2*i+1]:loc[2*i+1]]
+		}
+	}
+	return result
+}
+
 func popLastToken(state *parserState) *processedToken {
 	state.processedTokens = state.processedTokens[:len(state.processedTokens)-1]
 	if len(state.processedTokens) > 0 {
@@ -109,125 +288,344 @@ func popLastToken(state *parserState) *processedToken {
 }
 
 // Compile parses the given glob pattern and convertes it to a Glob. If no options are given, the DefaultOptions are
-// used.
+// used. Any error is a *PatternError.
 func Compile(pattern string, options *Options) (Glob, error) {
-	pattern = strings.TrimSpace(pattern)
-	reader := strings.NewReader(pattern)
+	trimmed := strings.TrimSpace(pattern)
+	leading := strings.Index(pattern, trimmed)
+	if options != nil && options.Strict && trimmed != pattern {
+		offset := 0
+		if leading == 0 {
+			offset = len(trimmed)
+		}
+		return nil, completePatternError(newPatternError(ErrSurroundingWhitespace, offset, "",
+			"leading or trailing whitespace"), pattern, 0)
+	}
 
+	glob, err := compile(trimmed, options)
+	if err != nil {
+		return nil, completePatternError(err, pattern, leading)
+	}
+	return glob, nil
+}
+
+func compile(pattern string, options *Options) (Glob, error) {
 	if options == nil {
 		options = DefaultOptions
 	} else {
-		// Check that the separator is not an expander
-		for _, expander := range expanders {
-			if options.Separator == expander {
-				return nil, fmt.Errorf("'%s' is not allowed as a separator", string(options.Separator))
+		// Check that neither separator is an expander
+		for _, expander := range specialRunes(options) {
+			for _, separator := range []rune{options.Separator, options.AltSeparator} {
+				if separator == expander {
+					return nil, newPatternError(ErrInvalidSeparator, -1, string(separator),
+						"'%s' is not allowed as a separator", string(separator))
+				}
 			}
 		}
 	}
 
+	excluded := new(strings.Builder)
+	for _, r := range options.excludedRunes() {
+		excluded.WriteString(escapeRegexComponent(string(r)))
+	}
+	escapedSeparator := escapeRegexComponent(string(options.Separator))
+	if options.AltSeparator != 0 && options.AltSeparator != options.Separator {
+		escapedSeparator = "[" + escapedSeparator + escapeRegexComponent(string(options.AltSeparator)) + "]"
+	}
 	state := &parserState{
 		options:          options,
-		escapedSeparator: escapeRegexComponent(string(options.Separator)),
+		escapedSeparator: escapedSeparator,
+		excludedRunes:    excluded.String(),
 		processedTokens:  make([]processedToken, 0, 10),
+		captureNames:     make(map[string]bool),
 	}
 	glob := &globImpl{
 		Regexp:      nil,
 		globPattern: pattern,
 		negated:     false,
 		parserState: state,
+		options:     options,
+		prefix:      &prefixMatcher{},
 	}
 
 	regexBuf := new(bytes.Buffer)
+	regexBuf.WriteString(regexFlags(options))
 	if options.MatchAtStart {
 		regexBuf.WriteRune('^')
 	}
 
-	var err error
 	// Transform into a regular expression pattern
 	// 1. Parse negation prefixes
-	err = parseNegation(reader, glob)
+	remainder, err := parseNegation(pattern, glob)
 	if err != nil {
 		return nil, err
 	}
 
 	// 2. Tokenise and convert!
-	tokeniser := newGlobTokeniser(reader, options)
+	tokeniser := newGlobTokeniser(strings.NewReader(remainder), options)
+	tokeniser.offset = len(pattern) - len(remainder)
+	if err = parseTokens(glob, tokeniser); err != nil {
+		return nil, err
+	}
+
+	for _, t := range state.processedTokens {
+		if !t.negatedGroup {
+			regexBuf.Write(t.contents.Bytes())
+		}
+	}
+
+	if options.MatchAtEnd {
+		regexBuf.WriteRune('$')
+	}
+
+	regexString := regexBuf.String()
+	Logger.Tracef("[ohmyglob:Glob] Compiled \"%s\" to regex `%s` (negated: %v)", pattern, regexString, glob.negated)
+	re, err := regexp.Compile(regexString)
+	if err != nil {
+		return nil, err
+	}
+
+	glob.Regexp = re
+	glob.literal = findRequiredLiteral(state)
+	glob.specificity = computeSpecificity(state)
+	glob.root = findWalkRoot(state)
+	if state.bracedGlobStar && state.negatedGroups == 0 {
+		expandedGlob, err := newExpandedGlob(glob, remainder, len(pattern)-len(remainder))
+		glob.parserState = nil
+		if err != nil {
+			return nil, err
+		}
+		return expandedGlob, nil
+	}
+	if state.negatedGroups > 0 {
+		negatedGlob, err := newNegatedGroupGlob(glob)
+		glob.parserState = nil
+		return negatedGlob, err
+	}
+	if fastGlob := newFastPathGlob(glob); fastGlob != nil {
+		glob.parserState = nil
+		return fastGlob, nil
+	}
+	if nativeGlob := newNativeGlob(glob, remainder); nativeGlob != nil {
+		glob.parserState = nil
+		return nativeGlob, nil
+	}
+
+	glob.parserState = nil
+	return glob, nil
+}
+
+// parseNegation consumes any negation prefixes from the pattern, returning the remainder
+func parseNegation(pattern string, glob *globImpl) (string, error) {
+	prefix, remainder := splitNegation(pattern, glob.parserState.options)
+	if len(remainder) == 0 {
+		return "", &PatternError{
+			Index:  -1,
+			Offset: -1,
+			Kind:   ErrEmptyPattern,
+			Err:    emptyPatternError{},
+		}
+	}
+	glob.negated = len(prefix)%2 == 1
+	return remainder, nil
+}
+
+// splitNegation splits the pattern into its negation prefixes and the remainder. When extended globs are enabled, a !
+// which opens a negated group is not a negation prefix.
+func splitNegation(pattern string, options *Options) (string, string) {
+	i := 0
+	for i < len(pattern) && pattern[i] == '!' && !(options.ExtGlob && strings.HasPrefix(pattern[i:], "!(")) {
+		i++
+	}
+	return pattern[:i], pattern[i:]
+}
+
+// parseTokens converts the tokens yielded by the tokeniser, appending them to the Glob's processed tokens
+func parseTokens(glob *globImpl, tokeniser *globTokeniser) error {
+	state := glob.parserState
+	options := state.options
+	var err error
 	lastProcessedToken := &processedToken{}
+	// The type of the last token yielded by the tokeniser (which, unlike the last processed token, is never removed)
+	lastTokenType := tcUnknown
+	// The type of the last token yielded by the tokeniser which wasn't a separator
+	lastNonSeparatorType := tcUnknown
+	// The types of all the tokens yielded by the tokeniser
+	tokenTypes := make([]tc, 0, 16)
 	for tokeniser.Scan() {
 		if err = tokeniser.Err(); err != nil {
-			return nil, err
+			return err
 		}
 
 		token, tokenType := tokeniser.Token()
+		state.tokenOffset = tokeniser.Offset()
 		t := processedToken{
 			contents:  nil,
+			token:     token,
 			tokenType: tokenType,
+			offset:    state.tokenOffset,
 		}
 
+		if options.Strict {
+			if err = checkStrict(t, lastTokenType, tokeniser); err != nil {
+				return err
+			}
+		}
+		if name := captureName(token); name != "" {
+			if state.captureNames[name] {
+				return newPatternError(ErrDuplicateCaptureName, state.tokenOffset, token,
+					"duplicate capture name \"%s\"", name)
+			}
+			state.captureNames[name] = true
+		}
+		lastTokenType = tokenType
+		tokenTypes = append(tokenTypes, tokenType)
+
 		// Special cases
+		swallowedSeparator := false
 		if tokenType == tcGlobStar && tokeniser.Peek() {
 			// If this is a globstar and the next token is a separator, consume it (the globstar pattern itself includes
 			// a separator)
 			if err = tokeniser.PeekErr(); err != nil {
-				return nil, err
+				return err
 			}
 			_, peekedType := tokeniser.PeekToken()
 			if peekedType == tcSeparator {
 				tokeniser.Scan()
+				swallowedSeparator = true
+				lastTokenType = tcSeparator
+				tokenTypes = append(tokenTypes, tcSeparator)
 			}
 		}
+		if tokenType == tcGlobStar &&
+			(isBraceToken(lastNonSeparatorType) || isBraceToken(peekTokenType(tokeniser))) {
+			state.bracedGlobStar = true
+		}
+		if tokenType != tcSeparator {
+			lastNonSeparatorType = tokenType
+		}
+		if tokenType == tcGlobStar {
+			// A globstar is last if nothing follows it in its alternative; within a brace expression, a separator which
+			// was swallowed by the globstar must still be matched
+			state.globStarIsLast = isEndOfAlternative(tokeniser) && !(swallowedSeparator && tokeniser.Peek())
+		}
 		if tokenType == tcGlobStar && lastProcessedToken.tokenType == tcGlobStar {
 			// If the last token was a globstar and this is too, remove the last. We don't remove this globstar because
-			// it may now be the last in the pattern, which is special
+			// it may now be the last in the pattern, which is special (but it takes the name of the last, if it has
+			// one, and captures the text of the last's group too)
+			if captureName(token) == "" {
+				token = lastProcessedToken.token
+				t.token = token
+			}
+			t.mergedOffsets = append(append([]int(nil), lastProcessedToken.mergedOffsets...), lastProcessedToken.offset)
 			lastProcessedToken = popLastToken(state)
 		}
-		if tokenType == tcGlobStar && lastProcessedToken.tokenType == tcSeparator && !tokeniser.Peek() {
-			// If this is the last token, and it's a globstar, remove a preceeding separator
+		if tokenType == tcGlobStar && lastProcessedToken.tokenType == tcSeparator && state.globStarIsLast &&
+			!beginsWithRoot(state) {
+			// If this is the last token (of the pattern or of a brace alternative), and it's a globstar, remove a
+			// preceeding separator
 			lastProcessedToken = popLastToken(state)
 		}
 
+		t.negatedGroup = state.inNegatedGroup
 		t.contents, err = processToken(token, tokenType, glob, tokeniser)
 		if err != nil {
-			return nil, err
+			return err
 		}
 
 		lastProcessedToken = &t
 		state.processedTokens = append(state.processedTokens, t)
 	}
+	if err = tokeniser.Err(); err != nil {
+		return err
+	}
+	if bracesGlueStars(tokenTypes) {
+		state.bracedGlobStar = true
+	}
+	return nil
+}
 
-	for _, t := range state.processedTokens {
-		t.contents.WriteTo(regexBuf)
+// checkStrict rejects ambiguous uses of stars when parsing strictly: runs of three or more, and globstars which are not
+// a whole path segment
+func checkStrict(t processedToken, lastTokenType tc, tokeniser *globTokeniser) error {
+	if t.tokenType != tcGlobStar {
+		return nil
 	}
 
-	if options.MatchAtEnd {
-		regexBuf.WriteRune('$')
+	nextToken, nextType := "", tcUnknown
+	if tokeniser.Peek() {
+		nextToken, nextType = tokeniser.PeekToken()
+	}
+	if nextType == tcStar || nextType == tcGlobStar {
+		return newPatternError(ErrAmbiguousStar, t.offset, t.token+nextToken, "ambiguous run of stars \"%s\"",
+			t.token+nextToken)
 	}
 
-	regexString := regexBuf.String()
-	Logger.Tracef("[ohmyglob:Glob] Compiled \"%s\" to regex `%s` (negated: %v)", pattern, regexString, glob.negated)
-	re, err := regexp.Compile(regexString)
-	if err != nil {
-		return nil, err
+	startsSegment := lastTokenType == tcUnknown || lastTokenType == tcSeparator ||
+		lastTokenType == tcBraceOpen || lastTokenType == tcBraceSeparator ||
+		lastTokenType == tcExtGlobOpen || lastTokenType == tcExtGlobSeparator
+	endsSegment := nextType == tcSeparator || isEndOfAlternative(tokeniser)
+	if !startsSegment || !endsSegment {
+		return newPatternError(ErrAmbiguousStar, t.offset, t.token, "globstar is not a whole path segment")
 	}
+	return nil
+}
 
-	glob.parserState = nil
-	glob.Regexp = re
+// isEndOfAlternative returns whether the tokeniser's current token is the last in the pattern, or the last in an
+// alternative within a brace expression or extended glob group
+func isEndOfAlternative(tokeniser *globTokeniser) bool {
+	if !tokeniser.Peek() {
+		return true
+	}
+	_, peekedType := tokeniser.PeekToken()
+	return peekedType == tcBraceSeparator || peekedType == tcBraceClose ||
+		peekedType == tcExtGlobSeparator || peekedType == tcExtGlobClose
+}
 
-	return glob, nil
+// isBraceToken returns whether the token type opens, separates the alternatives of, or closes a brace expression
+func isBraceToken(tokenType tc) bool {
+	return tokenType == tcBraceOpen || tokenType == tcBraceSeparator || tokenType == tcBraceClose
 }
 
-func parseNegation(r io.RuneScanner, glob *globImpl) error {
-	for {
-		char, _, err := r.ReadRune()
+// peekTokenType returns the type of the next token, or tcUnknown if there is none
+func peekTokenType(tokeniser *globTokeniser) tc {
+	if !tokeniser.Peek() {
+		return tcUnknown
+	}
+	_, peekedType := tokeniser.PeekToken()
+	return peekedType
+}
 
-		if err != nil {
-			return err
-		} else if char == '!' {
-			glob.negated = !glob.negated
-		} else {
-			r.UnreadRune()
-			return nil
-		}
+// isStartOfAlternative returns whether no tokens have yet been processed in the pattern, or in the current alternative
+// within a brace expression or extended glob group
+func isStartOfAlternative(state *parserState) bool {
+	if len(state.processedTokens) == 0 {
+		return true
+	}
+	lastType := state.processedTokens[len(state.processedTokens)-1].tokenType
+	return lastType == tcBraceOpen || lastType == tcBraceSeparator ||
+		lastType == tcExtGlobOpen || lastType == tcExtGlobSeparator
+}
+
+// beginsWithRoot reports whether the only token processed so far is a separator which, with VolumeRoots, is the root of
+// the pattern (eg. "\**" matches only paths beginning with a separator), so must be kept before a trailing globstar
+func beginsWithRoot(state *parserState) bool {
+	return state.options.VolumeRoots && len(state.processedTokens) == 1 &&
+		state.processedTokens[0].tokenType == tcSeparator
+}
+
+// isCapturing returns whether tokens of the type are captured as a group of the regular expression
+func isCapturing(tokenType tc) bool {
+	return tokenType == tcGlobStar || tokenType == tcStar || tokenType == tcAny || tokenType == tcCharClass
+}
+
+// openCapture opens the group capturing a wildcard, naming it if the wildcard is a named capture
+func openCapture(buf *bytes.Buffer, token string) {
+	if name := captureName(token); name != "" {
+		buf.WriteString("(?P<")
+		buf.WriteString(name)
+		buf.WriteString(">")
+	} else {
+		buf.WriteString("(")
 	}
 }
 
@@ -239,28 +637,119 @@ func processToken(token string, tokenType tc, glob *globImpl, tokeniser *globTok
 	case tcGlobStar:
 		// Globstars also take care of surrounding separators; separator components before and after a globstar are
 		// suppressed
-		isLast := !tokeniser.Peek()
+		isLast := state.globStarIsLast
 		buf.WriteString("(?:")
-		if isLast && len(glob.parserState.processedTokens) > 0 {
+		if isLast && !isStartOfAlternative(state) && !beginsWithRoot(state) {
 			buf.WriteString(state.escapedSeparator)
 		}
-		buf.WriteString(".+")
+		// Unlike the other wildcards, globstars don't match newlines; the text between the surrounding separators is
+		// captured
+		openCapture(buf, token)
+		if state.options.VolumeRoots {
+			// The text can't begin with a separator (as a UNC prefix does), or contain a colon
+			buf.WriteString("[^")
+			buf.WriteString(state.excludedRunes)
+			buf.WriteString("\\n][^:\\n]*)")
+		} else {
+			buf.WriteString(".+)")
+		}
 		if !isLast {
 			buf.WriteString(state.escapedSeparator)
 		}
 		buf.WriteString(")?")
 	case tcStar:
+		openCapture(buf, token)
 		buf.WriteString("[^")
-		buf.WriteString(state.escapedSeparator)
-		buf.WriteString("]*")
+		buf.WriteString(state.excludedRunes)
+		buf.WriteString("]*)")
 	case tcAny:
+		openCapture(buf, token)
 		buf.WriteString("[^")
-		buf.WriteString(state.escapedSeparator)
-		buf.WriteString("]")
+		buf.WriteString(state.excludedRunes)
+		buf.WriteString("])")
+	case tcCharClass:
+		class, err := parseCharClass(token, state.tokenOffset, state.options.escaper())
+		if err != nil {
+			return nil, err
+		}
+		if state.options.CaseFolding == FoldASCII {
+			class.foldASCII()
+		}
+		openCapture(buf, token)
+		buf.WriteString(class.regexString(state.options.excludedRunes()))
+		buf.WriteString(")")
 	case tcSeparator:
 		buf.WriteString(state.escapedSeparator)
+	case tcBraceOpen:
+		state.groupStack = append(state.groupStack, token)
+		buf.WriteString("(?:")
+	case tcBraceSeparator:
+		buf.WriteString("|")
+	case tcBraceClose:
+		// A brace expression containing only a sequence expression (eg. {1..10}) matches any of the sequence's values
+		n := len(state.processedTokens)
+		if n >= 2 && state.processedTokens[n-1].tokenType == tcLiteral && state.processedTokens[n-2].tokenType == tcBraceOpen {
+			sequenceToken := state.processedTokens[n-1]
+			values, isSequence, err := expandSequenceExpression(sequenceToken.token, state.processedTokens[n-2].offset)
+			if err != nil {
+				return nil, err
+			}
+			if isSequence {
+				sequenceToken.contents.Reset()
+				for i, value := range values {
+					if i > 0 {
+						sequenceToken.contents.WriteRune('|')
+					}
+					sequenceToken.contents.WriteString(literalRegexComponent(value, state.options))
+				}
+			}
+		}
+		if state.groupStack[len(state.groupStack)-1] != "{" {
+			return nil, newPatternError(ErrMismatchedGroup, state.tokenOffset, token,
+				"brace expression closed within unclosed extended glob group")
+		}
+		state.groupStack = state.groupStack[:len(state.groupStack)-1]
+		buf.WriteString(")")
+	case tcExtGlobOpen:
+		if token == "!(" {
+			if len(state.groupStack) > 0 {
+				return nil, newPatternError(ErrNestedNegatedGroup, state.tokenOffset, token,
+					"negated extended glob groups may not be nested within other groups")
+			}
+			// A negated group can't be expressed in a regular expression; it is approximated by a wildcard, and its
+			// contents are matched separately
+			state.negatedGroups++
+			state.inNegatedGroup = true
+			buf.WriteString("[^")
+			buf.WriteString(state.excludedRunes)
+			buf.WriteString("]*")
+		} else {
+			buf.WriteString("(?:")
+		}
+		state.groupStack = append(state.groupStack, token)
+	case tcExtGlobSeparator:
+		buf.WriteString("|")
+	case tcExtGlobClose:
+		opener := state.groupStack[len(state.groupStack)-1]
+		if opener == "{" {
+			return nil, newPatternError(ErrMismatchedGroup, state.tokenOffset, token,
+				"extended glob group closed within unclosed brace expression")
+		}
+		state.groupStack = state.groupStack[:len(state.groupStack)-1]
+		switch opener {
+		case "?(":
+			buf.WriteString(")?")
+		case "*(":
+			buf.WriteString(")*")
+		case "+(":
+			buf.WriteString(")+")
+		case "@(":
+			buf.WriteString(")")
+		case "!(":
+			state.inNegatedGroup = false
+		}
 	case tcLiteral:
-		buf.WriteString(escapeRegexComponent(token))
+		buf.WriteString(literalRegexComponent(token, state.options))
 	}
 
 	return buf, nil
@@ -2,6 +2,7 @@ package ohmyglob
 
 import (
 	"os"
+	"strings"
 	"testing"
 
 	log "github.com/cihub/seelog"
@@ -111,6 +112,29 @@ func TestGlobStar(t *testing.T) {
 	assert.True(t, glob.MatchString(match), "%s should match %s", pattern, match)
 	match = "aaaa/bbb////c///def/////****/*/.?..**//."
 	assert.True(t, glob.MatchString(match), "%s should match %s", pattern, match)
+
+	// Unlike stars, globstars don't match newlines
+	for pattern, matches := range map[string][2][]string{
+		"**":        {{"a/b"}, {"a\nb", "\n"}},
+		"a/**":      {{"a/b/c"}, {"a/b\nc", "a/\n/c"}},
+		"**/*.go":   {{"a/b.go", "a/b\nc.go"}, {"a\nb/c.go"}},
+		"a/**/b":    {{"a/x/b"}, {"a/x\ny/b"}},
+		"a/**/?":    {{"a/x/b", "a/x/\n"}, {"a/x\ny/b"}},
+		"{a,b}/**":  {{"a/x"}, {"a/x\ny"}},
+		"a/*/b":     {{"a/x\ny/b"}, {}},
+		"a/**/[xy]": {{"a/b/x"}, {"a/\n/y"}},
+	} {
+		glob, err = Compile(pattern, nil)
+		assert.NoError(t, err)
+		for _, match := range matches[0] {
+			assert.True(t, glob.MatchString(match), "%q should match %q", pattern, match)
+			assert.True(t, glob.Match([]byte(match)), "%q should match %q", pattern, match)
+		}
+		for _, match := range matches[1] {
+			assert.False(t, glob.MatchString(match), "%q should not match %q", pattern, match)
+			assert.False(t, glob.Match([]byte(match)), "%q should not match %q", pattern, match)
+		}
+	}
 }
 
 // Check that setting MatchAtStart to false allows any prefix
@@ -191,6 +215,19 @@ func TestCustomSeparator(t *testing.T) {
 	assert.True(t, glob.MatchString(match), "%s should match %s", pattern, match)
 	match = "foo.bar"
 	assert.False(t, glob.MatchString(match), "%s should not match %s", pattern, match)
+
+	// Runes which only have meaning within extended glob groups can be separators when they're disabled
+	for _, separator := range []rune{'|', ')', '+', '@'} {
+		sep := string(separator)
+		pattern = "**" + sep + "b"
+		glob, err = Compile(pattern, &Options{Separator: separator, MatchAtStart: true, MatchAtEnd: true})
+		assert.NoError(t, err)
+		for _, match := range []string{"a" + sep + "b", "x" + sep + "y" + sep + "b", "b"} {
+			assert.True(t, glob.MatchString(match), "%s should match %s", pattern, match)
+		}
+		match = "ab"
+		assert.False(t, glob.MatchString(match), "%s should not match %s", pattern, match)
+	}
 }
 
 // Illegal separators should return an error on construction
@@ -206,6 +243,97 @@ func TestIllegalSeparator(t *testing.T) {
 	assert.Error(t, err, "\\ should not be allowed as a separator")
 }
 
+// Windows paths use either slash as a separator, and are rooted at a drive letter or UNC prefix
+func TestWindowsOptions(t *testing.T) {
+	// Maps patterns to the strings they should, and shouldn't, match
+	expectations := map[string][2][]string{
+		`src\**\*.go`: {
+			{`src\main.go`, `src/pkg/util.go`, `SRC\Pkg/Util.GO`},
+			{`src\pkg`, `C:\src\main.go`, `\\server\share\src\main.go`, `lib\src\main.go`},
+		},
+		`**\*.txt`: {
+			{`a.txt`, `docs\a.txt`, `docs/notes/a.txt`},
+			{`C:\docs\a.txt`, `\\server\share\a.txt`, `c:a.txt`},
+		},
+		`C:\Users\*\Documents\**`: {
+			{`C:\Users\ann\Documents`, `c:/users/ann/documents/work/report.doc`},
+			{`D:\Users\ann\Documents\report.doc`, `C:\Users\ann\Desktop\report.doc`, `Users\ann\Documents\report.doc`},
+		},
+		`?:\**`: {
+			{`C:`, `d:\games\save.dat`},
+			{`games\save.dat`, `\\server\share\x`},
+		},
+		`\\server\share\**`: {
+			{`\\server\share\docs\a.txt`, `//SERVER/share/a.txt`, `\\server\share`},
+			{`\\server\other\a.txt`, `C:\server\share\a.txt`, `server\share\a.txt`},
+		},
+		`\**`: {
+			{`\`, `\Windows\System32`, `/Windows`},
+			{`Windows`, `\\server\share`, `C:\Windows`},
+		},
+		"file`[1`].txt": {
+			{`file[1].txt`, `FILE[1].TXT`},
+			{`file1.txt`},
+		},
+		`[!x]*`: {
+			{`abc`},
+			{`\abc`, `/abc`, `:abc`, `C:`},
+		},
+	}
+
+	for pattern, expectation := range expectations {
+		glob, err := Compile(pattern, WindowsOptions)
+		if !assert.NoError(t, err, "Pattern %s should compile", pattern) {
+			continue
+		}
+		for _, s := range expectation[0] {
+			assert.True(t, glob.MatchString(s), "%s should match %s", pattern, s)
+		}
+		for _, s := range expectation[1] {
+			assert.False(t, glob.MatchString(s), "%s should not match %s", pattern, s)
+		}
+	}
+
+	// A backslash can't be a separator while it is also the Escaper
+	_, err := Compile("foo", &Options{Separator: '/', AltSeparator: '\\'})
+	assert.Error(t, err, "\\ should not be allowed as a separator")
+}
+
+// An additional separator is honoured by every kind of Glob, including those which are usually matched natively
+func TestAltSeparator(t *testing.T) {
+	options := &Options{
+		Separator:    '/',
+		AltSeparator: '\\',
+		Escaper:      '^',
+		MatchAtStart: true,
+		MatchAtEnd:   true,
+		ExtGlob:      true,
+	}
+	expectations := map[string][2][]string{
+		`foo/bar`:         {{`foo/bar`, `foo\bar`}, {`foo/baz`, `Foo/bar`}},
+		`foo/**`:          {{`foo/bar`, `foo\bar/baz`}, {`foobar`}},
+		`**/*.go`:         {{`a.go`, `a\b/c.go`}, {`a\b/c.gox`}},
+		`!(*.test).go`:    {{`main.go`}, {`main.test.go`, `a\main.go`}},
+		`src/{a,b}/x^*`:   {{`src\a\x*`, `src/b\x*`}, {`src/a/xy`}},
+		`vendor/^\*/*.go`: {{`vendor/\*/a.go`}, {`vendor/x/a.go`}},
+	}
+	for pattern, expectation := range expectations {
+		glob, err := Compile(pattern, options)
+		if !assert.NoError(t, err, "Pattern %s should compile", pattern) {
+			continue
+		}
+		set, err := NewGlobSet([]Glob{glob})
+		assert.NoError(t, err)
+		for _, s := range expectation[0] {
+			assert.True(t, glob.MatchString(s), "%s should match %s", pattern, s)
+			assert.True(t, set.MatchString(s), "%s should match %s in a set", pattern, s)
+		}
+		for _, s := range expectation[1] {
+			assert.False(t, glob.MatchString(s), "%s should not match %s", pattern, s)
+		}
+	}
+}
+
 // Meaningful can be escaped with a backslash
 func TestEscaping(t *testing.T) {
 	// Maps to a pair of (should, shouldn't) strings
@@ -231,6 +359,572 @@ func TestEscaping(t *testing.T) {
 	// Custom separator
 }
 
+func TestCharacterClass(t *testing.T) {
+	// Maps to a pair of (should, shouldn't) string slices
+	expectations := map[string][2][]string{
+		`log-[0-9][0-9].txt`: [2][]string{
+			[]string{`log-00.txt`, `log-42.txt`},
+			[]string{`log-1.txt`, `log-a1.txt`, `log-123.txt`},
+		},
+		`[abc]`: [2][]string{
+			[]string{`a`, `b`, `c`},
+			[]string{`d`, `ab`, ``},
+		},
+		`[!abc]`: [2][]string{
+			[]string{`d`, `∆`},
+			[]string{`a`, `b`, `c`, `/`},
+		},
+		`[^a-y]z`: [2][]string{
+			[]string{`zz`, `Az`},
+			[]string{`az`, `yz`, `/z`},
+		},
+		`[]-]`: [2][]string{
+			[]string{`]`, `-`},
+			[]string{`a`},
+		},
+		`[a-]`: [2][]string{
+			[]string{`a`, `-`},
+			[]string{`b`},
+		},
+		`[a\-z]`: [2][]string{
+			[]string{`a`, `-`, `z`},
+			[]string{`b`},
+		},
+		`[\]\\]`: [2][]string{
+			[]string{`]`, `\`},
+			[]string{`a`},
+		},
+		`[∆-∆˙]`: [2][]string{
+			[]string{`∆`, `˙`},
+			[]string{`a`},
+		},
+		// The separator is never matched by a class, even if it is specified explicitly
+		`foo[/a]bar`: [2][]string{
+			[]string{`fooabar`},
+			[]string{`foo/bar`},
+		},
+		`foo[/]bar`: [2][]string{
+			[]string{},
+			[]string{`foo/bar`, `foobar`},
+		},
+		`foo\[bar]`: [2][]string{
+			[]string{`foo[bar]`},
+			[]string{`foob`},
+		},
+		`foo/**/[a-c]*.go`: [2][]string{
+			[]string{`foo/a.go`, `foo/bar/baz/cat.go`},
+			[]string{`foo/bar/dog.go`, `foo/a.gox`},
+		},
+	}
+
+	for pattern, s := range expectations {
+		glob, err := Compile(pattern, DefaultOptions)
+		if !assert.NoError(t, err, "Compiling `%s`", pattern) {
+			continue
+		}
+
+		for _, should := range s[0] {
+			assert.True(t, glob.MatchString(should), "Glob `%s` should match `%s`", pattern, should)
+		}
+		for _, shouldnt := range s[1] {
+			assert.False(t, glob.MatchString(shouldnt), "Glob `%s` should not match `%s`", pattern, shouldnt)
+		}
+	}
+
+	// With a custom separator, that is the separator that can't be matched
+	glob, err := Compile(`foo.[!a]`, &Options{
+		Separator:    '.',
+		MatchAtStart: true,
+		MatchAtEnd:   true,
+	})
+	assert.NoError(t, err)
+	assert.True(t, glob.MatchString(`foo./`))
+	assert.False(t, glob.MatchString(`foo..`))
+}
+
+func TestPOSIXCharacterClass(t *testing.T) {
+	// Maps to a pair of (should, shouldn't) string slices
+	expectations := map[string][2][]string{
+		`[[:alpha:]]`: [2][]string{
+			[]string{`a`, `Z`, `é`, `这`, `ж`},
+			[]string{`1`, `_`, `∆`, ` `},
+		},
+		`[[:digit:]][[:digit:]]`: [2][]string{
+			[]string{`42`, `٤٢`},
+			[]string{`4a`, `4`},
+		},
+		`[[:upper:]]*`: [2][]string{
+			[]string{`Foo`, `Éclair`},
+			[]string{`foo`, `éclair`, `1Foo`},
+		},
+		`[[:lower:][:digit:]_]`: [2][]string{
+			[]string{`a`, `ß`, `1`, `_`},
+			[]string{`A`, `-`},
+		},
+		`[![:space:]]`: [2][]string{
+			[]string{`a`, `∆`},
+			[]string{` `, "\t", " ", `/`},
+		},
+		`[[:alnum:]-]`: [2][]string{
+			[]string{`a`, `9`, `-`},
+			[]string{`_`},
+		},
+		`[[:xdigit:]]`: [2][]string{
+			[]string{`0`, `f`, `F`},
+			[]string{`g`},
+		},
+		`[[:blank:]]`: [2][]string{
+			[]string{` `, "\t"},
+			[]string{"\n"},
+		},
+		// The separator is punctuation, but can never be matched by a class
+		`a[[:punct:]]b`: [2][]string{
+			[]string{`a.b`, `a-b`, `a$b`, `a¿b`},
+			[]string{`a/b`, `axb`},
+		},
+	}
+
+	for pattern, s := range expectations {
+		glob, err := Compile(pattern, DefaultOptions)
+		if !assert.NoError(t, err, "Compiling `%s`", pattern) {
+			continue
+		}
+
+		for _, should := range s[0] {
+			assert.True(t, glob.MatchString(should), "Glob `%s` should match `%s`", pattern, should)
+		}
+		for _, shouldnt := range s[1] {
+			assert.False(t, glob.MatchString(shouldnt), "Glob `%s` should not match `%s`", pattern, shouldnt)
+		}
+	}
+
+	// Unknown class names are reported along with their offset
+	_, err := Compile(`foo/[a[:alphabet:]]`, DefaultOptions)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), `[:alphabet:]`)
+		assert.Contains(t, err.Error(), `offset 6`)
+	}
+	_, err = Compile(`!∆[[:nope:]]`, DefaultOptions)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), `[:nope:]`)
+		assert.Contains(t, err.Error(), `offset 5`)
+	}
+}
+
+func TestInvalidCharacterClass(t *testing.T) {
+	for _, pattern := range []string{`foo/[z-a]`, `[[:nope:]]`} {
+		_, err := Compile(pattern, DefaultOptions)
+		assert.Error(t, err, "Compiling `%s` should fail", pattern)
+	}
+
+	// An opening bracket which isn't closed is literal, unless parsing strictly
+	strictOptions := &Options{Separator: '/', MatchAtStart: true, MatchAtEnd: true, Strict: true}
+	for pattern, should := range map[string]string{`foo[`: `foo[`, `a[b`: `a[b`, `[]`: `[]`, `**/[`: `a/[`} {
+		glob, err := Compile(pattern, DefaultOptions)
+		if assert.NoError(t, err, "Compiling `%s` should succeed", pattern) {
+			assert.True(t, glob.MatchString(should), "`%s` should match `%s`", pattern, should)
+			assert.False(t, glob.MatchString(should[:len(should)-1]+`x`), "`%s` should be literal", pattern)
+		}
+		_, err = Compile(pattern, strictOptions)
+		assert.Error(t, err, "Compiling `%s` strictly should fail", pattern)
+	}
+}
+
+func TestBraceExpansion(t *testing.T) {
+	// Maps to a pair of (should, shouldn't) string slices
+	expectations := map[string][2][]string{
+		`src/**/*.{go,proto}`: [2][]string{
+			[]string{`src/main.go`, `src/foo/bar.proto`},
+			[]string{`src/main.c`, `src/main.go.bak`, `main.go`},
+		},
+		`foo{,.bak}`: [2][]string{
+			[]string{`foo`, `foo.bak`},
+			[]string{`foo.`, `foobak`},
+		},
+		`{a,b{c,d{e,f}}}`: [2][]string{
+			[]string{`a`, `bc`, `bde`, `bdf`},
+			[]string{`b`, `bd`, `bdg`, `abc`},
+		},
+		`{foo/**,bar}`: [2][]string{
+			[]string{`foo`, `foo/a/b`, `bar`},
+			[]string{`foobar`, `bar/a`},
+		},
+		`{**/,}baz`: [2][]string{
+			[]string{`baz`, `a/baz`, `a/b/baz`},
+			[]string{`abaz`},
+		},
+		`{a,b}/**`: [2][]string{
+			[]string{`a`, `b/c/d`},
+			[]string{`c/d`, `ab`},
+		},
+		// Commas and closing braces outside of a brace expression are literal
+		`a,b}`: [2][]string{
+			[]string{`a,b}`},
+			[]string{`a`, `b`},
+		},
+		`\{a,b\}`: [2][]string{
+			[]string{`{a,b}`},
+			[]string{`a`, `b`},
+		},
+		`{a\,b,c}`: [2][]string{
+			[]string{`a,b`, `c`},
+			[]string{`a`, `b`},
+		},
+		// As in bash, braces are literal unless they are closed and contain a comma or a sequence
+		`{a}`: [2][]string{
+			[]string{`{a}`},
+			[]string{`a`},
+		},
+		`{foo`: [2][]string{
+			[]string{`{foo`},
+			[]string{`foo`},
+		},
+		`foo/{a,{b,c}`: [2][]string{
+			[]string{`foo/{a,b`, `foo/{a,c`},
+			[]string{`foo/a`, `foo/b`},
+		},
+		`{a,{b}}`: [2][]string{
+			[]string{`a`, `{b}`},
+			[]string{`b`},
+		},
+		`{a{,b}c}`: [2][]string{
+			[]string{`{ac}`, `{abc}`},
+			[]string{`ac`, `abc`},
+		},
+		`{[,}],x}`: [2][]string{
+			[]string{`,`, `}`, `x`},
+			[]string{`[`},
+		},
+	}
+
+	for pattern, s := range expectations {
+		glob, err := Compile(pattern, DefaultOptions)
+		if !assert.NoError(t, err, "Compiling `%s`", pattern) {
+			continue
+		}
+
+		for _, should := range s[0] {
+			assert.True(t, glob.MatchString(should), "Glob `%s` should match `%s`", pattern, should)
+		}
+		for _, shouldnt := range s[1] {
+			assert.False(t, glob.MatchString(shouldnt), "Glob `%s` should not match `%s`", pattern, shouldnt)
+		}
+	}
+
+	// Unterminated braces are likely to be mistakes, so are rejected when parsing strictly
+	strictOptions := &Options{Separator: '/', MatchAtStart: true, MatchAtEnd: true, Strict: true}
+	for _, pattern := range []string{`{foo`, `foo/{a,{b,c}`} {
+		_, err := Compile(pattern, strictOptions)
+		assert.Error(t, err, "Compiling `%s` should fail", pattern)
+	}
+
+	// Commas can be separators, leaving braces only sequence expressions
+	glob, err := Compile(`a,{b,c},{1..2}`, &Options{Separator: ',', MatchAtStart: true, MatchAtEnd: true})
+	assert.NoError(t, err)
+	assert.True(t, glob.MatchString(`a,{b,c},2`))
+	assert.False(t, glob.MatchString(`a,b,2`))
+	assert.False(t, glob.MatchString(`a,{b,c},{1..2}`))
+}
+
+func TestBraceSequences(t *testing.T) {
+	// Maps to a pair of (should, shouldn't) string slices
+	expectations := map[string][2][]string{
+		`shard-{00..63}/part-*`: [2][]string{
+			[]string{`shard-00/part-0`, `shard-07/part-1`, `shard-63/part-2`},
+			[]string{`shard-7/part-0`, `shard-64/part-0`, `shard-000/part-0`},
+		},
+		`{0..100..10}`: [2][]string{
+			[]string{`0`, `10`, `50`, `100`},
+			[]string{`5`, `11`, `110`},
+		},
+		`log.{a..f}`: [2][]string{
+			[]string{`log.a`, `log.f`},
+			[]string{`log.g`, `log.A`},
+		},
+		`{10..-10..5}`: [2][]string{
+			[]string{`10`, `5`, `0`, `-5`, `-10`},
+			[]string{`1`, `-1`, `-15`},
+		},
+		// Nested within alternatives
+		`{v{1..3},latest}/*`: [2][]string{
+			[]string{`v1/a`, `v3/b`, `latest/c`},
+			[]string{`v4/a`, `v/a`},
+		},
+		// Escaping disables the sequence, leaving the braces literal
+		`{1\..3}`: [2][]string{
+			[]string{`{1..3}`},
+			[]string{`1..3`, `2`},
+		},
+	}
+
+	for pattern, s := range expectations {
+		glob, err := Compile(pattern, DefaultOptions)
+		if !assert.NoError(t, err, "Compiling `%s`", pattern) {
+			continue
+		}
+
+		for _, should := range s[0] {
+			assert.True(t, glob.MatchString(should), "Glob `%s` should match `%s`", pattern, should)
+		}
+		for _, shouldnt := range s[1] {
+			assert.False(t, glob.MatchString(shouldnt), "Glob `%s` should not match `%s`", pattern, shouldnt)
+		}
+	}
+
+	_, err := Compile(`shard-{0..1000000}`, DefaultOptions)
+	if assert.Error(t, err, "Absurdly large sequences should be rejected") {
+		assert.Contains(t, err.Error(), "{0..1000000}")
+	}
+}
+
+func TestExtGlob(t *testing.T) {
+	options := &Options{
+		Separator:    '/',
+		MatchAtStart: true,
+		MatchAtEnd:   true,
+		ExtGlob:      true,
+	}
+	// Maps to a pair of (should, shouldn't) string slices
+	expectations := map[string][2][]string{
+		`foo?(.bak)`: [2][]string{
+			[]string{`foo`, `foo.bak`},
+			[]string{`foo.bak.bak`, `foo.`},
+		},
+		`*(ab|cd).txt`: [2][]string{
+			[]string{`.txt`, `ab.txt`, `abcdab.txt`},
+			[]string{`abc.txt`, `ab/cd.txt`},
+		},
+		`+(ab|cd)`: [2][]string{
+			[]string{`ab`, `cdcd`, `abcd`},
+			[]string{``, `abc`},
+		},
+		`@(foo|bar)/*.go`: [2][]string{
+			[]string{`foo/a.go`, `bar/b.go`},
+			[]string{`foobar/a.go`, `baz/a.go`},
+		},
+		`src/**/@(*.go|*.proto)`: [2][]string{
+			[]string{`src/a.go`, `src/a/b/c.proto`},
+			[]string{`src/a.c`},
+		},
+		`!(*.test).go`: [2][]string{
+			[]string{`foo.go`, `foo.testing.go`, `.go`},
+			[]string{`foo.test.go`, `foo/bar.go`, `foo.gox`},
+		},
+		`pkg/!(internal|vendor)/*.go`: [2][]string{
+			[]string{`pkg/api/a.go`, `pkg/internals/b.go`, `pkg//c.go`},
+			[]string{`pkg/internal/a.go`, `pkg/vendor/b.go`, `pkg/a/b/c.go`},
+		},
+		`!(foo)`: [2][]string{
+			[]string{`bar`, `fooo`, ``},
+			[]string{`foo`, `bar/baz`},
+		},
+		`a!(b)!(c)d`: [2][]string{
+			[]string{`ad`, `axd`, `abd`, `acd`},
+			[]string{`a/d`, `ab`},
+		},
+		`!(@(a|b)*)x`: [2][]string{
+			[]string{`x`, `cx`, `cax`},
+			[]string{`ax`, `bcx`},
+		},
+		// A leading ! followed by a group is a negated group, not a negation prefix (as with any negative glob, a
+		// match is reported when the rest of the pattern matches)
+		`!!(a)`: [2][]string{
+			[]string{`b`},
+			[]string{`a`},
+		},
+		`\@(a)`: [2][]string{
+			[]string{`@(a)`},
+			[]string{`a`},
+		},
+	}
+
+	for pattern, s := range expectations {
+		glob, err := Compile(pattern, options)
+		if !assert.NoError(t, err, "Compiling `%s`", pattern) {
+			continue
+		}
+
+		for _, should := range s[0] {
+			assert.True(t, glob.MatchString(should), "Glob `%s` should match `%s`", pattern, should)
+			assert.True(t, glob.Match([]byte(should)), "Glob `%s` should match `%s`", pattern, should)
+		}
+		for _, shouldnt := range s[1] {
+			assert.False(t, glob.MatchString(shouldnt), "Glob `%s` should not match `%s`", pattern, shouldnt)
+			assert.False(t, glob.Match([]byte(shouldnt)), "Glob `%s` should not match `%s`", pattern, shouldnt)
+		}
+	}
+
+	// Long input is matched in a pass over the text for each possible start of a part, rather than by matching each part
+	// against every division of the input
+	glob, err := Compile(`**/!(x)`, options)
+	assert.NoError(t, err)
+	long := strings.Repeat("a/", 2000)
+	assert.True(t, glob.MatchString(long+"y"))
+	assert.False(t, glob.MatchString(long+"x"))
+	assert.Equal(t, []string{long + "y", long[:len(long)-1]}, glob.FindStringSubmatch(long+"y"))
+
+	glob, err = Compile(`!!(a)`, options)
+	assert.NoError(t, err)
+	assert.True(t, glob.IsNegative())
+
+	// Without the option, extended glob syntax is literal
+	glob, err = Compile(`+(ab|cd)`, DefaultOptions)
+	assert.NoError(t, err)
+	assert.True(t, glob.MatchString(`+(ab|cd)`))
+	assert.False(t, glob.MatchString(`ab`))
+
+	for _, pattern := range []string{`+(a`, `@(a|!(b))`, `{a,!(b)}`, `{a,@(b})`, `@(a|{b,c)}`} {
+		_, err := Compile(pattern, options)
+		assert.Error(t, err, "Compiling `%s` should fail", pattern)
+	}
+}
+
+func TestCaseFolding(t *testing.T) {
+	// Maps to a pair of (should, shouldn't) string slices, for each folding mode
+	expectations := map[CaseFolding]map[string][2][]string{
+		CaseSensitive: map[string][2][]string{
+			`*.txt`: [2][]string{
+				[]string{`a.txt`},
+				[]string{`a.TXT`, `a.Txt`},
+			},
+		},
+		FoldASCII: map[string][2][]string{
+			`*.txt`: [2][]string{
+				[]string{`a.txt`, `a.TXT`, `A.Txt`},
+				[]string{`a.tx`},
+			},
+			`Straße/**`: [2][]string{
+				[]string{`straße/a`, `STRAßE/a/b`},
+				[]string{`STRASSE/a`, `STRAẞE/a`},
+			},
+			`[a-c]x[!D-F]`: [2][]string{
+				[]string{`ax1`, `CXg`, `bXZ`},
+				[]string{`dx1`, `axd`, `axE`},
+			},
+			`[[:upper:]]*`: [2][]string{
+				[]string{`Foo`, `foo`},
+				[]string{`éclair`, `1foo`},
+			},
+			`{Foo,BAR}`: [2][]string{
+				[]string{`foo`, `FOO`, `bar`},
+				[]string{`baz`},
+			},
+			`log.{a..c}`: [2][]string{
+				[]string{`log.a`, `LOG.C`},
+				[]string{`log.d`},
+			},
+			// Only letters are folded; the Kelvin sign is not an ASCII letter
+			`k`: [2][]string{
+				[]string{`k`, `K`},
+				[]string{"\u212a"},
+			},
+		},
+		FoldUnicode: map[string][2][]string{
+			`*.txt`: [2][]string{
+				[]string{`a.txt`, `a.TXT`, `A.Txt`},
+				[]string{`a.tx`},
+			},
+			`ÉCLAIR/*`: [2][]string{
+				[]string{`éclair/a`, `Éclair/b`},
+				[]string{`eclair/a`},
+			},
+			`[α-γ]`: [2][]string{
+				[]string{`β`, `Β`},
+				[]string{`δ`, `Δ`},
+			},
+			`[!a]`: [2][]string{
+				[]string{`b`, `B`},
+				[]string{`a`, `A`, `/`},
+			},
+			`k`: [2][]string{
+				[]string{`k`, `K`, "\u212a"},
+				[]string{`x`},
+			},
+		},
+	}
+
+	for folding, es := range expectations {
+		options := &Options{
+			Separator:    '/',
+			MatchAtStart: true,
+			MatchAtEnd:   true,
+			CaseFolding:  folding,
+		}
+		for pattern, s := range es {
+			glob, err := Compile(pattern, options)
+			if !assert.NoError(t, err, "Compiling `%s`", pattern) {
+				continue
+			}
+
+			for _, should := range s[0] {
+				assert.True(t, glob.MatchString(should), "Glob `%s` (folding %d) should match `%s`", pattern, folding,
+					should)
+			}
+			for _, shouldnt := range s[1] {
+				assert.False(t, glob.MatchString(shouldnt), "Glob `%s` (folding %d) should not match `%s`", pattern,
+					folding, shouldnt)
+			}
+		}
+	}
+
+	// Negated extended glob groups are folded too
+	glob, err := Compile(`!(*.TEST).go`, &Options{
+		Separator:    '/',
+		MatchAtStart: true,
+		MatchAtEnd:   true,
+		CaseFolding:  FoldUnicode,
+		ExtGlob:      true,
+	})
+	if assert.NoError(t, err) {
+		assert.True(t, glob.MatchString(`foo.GO`))
+		assert.False(t, glob.MatchString(`foo.test.go`))
+	}
+}
+
+func TestStrict(t *testing.T) {
+	strict := &Options{
+		Separator:    '/',
+		MatchAtStart: true,
+		MatchAtEnd:   true,
+		Strict:       true,
+	}
+
+	// Maps patterns to the kind of error (and its offset) that strict parsing should produce
+	rejected := map[string]struct {
+		kind   PatternErrorKind
+		offset int
+	}{
+		` foo/*`:       {ErrSurroundingWhitespace, 0},
+		"foo/*\t":      {ErrSurroundingWhitespace, 5},
+		`foo\`:         {ErrDanglingEscaper, 3},
+		`foo/\\\`:      {ErrDanglingEscaper, 6},
+		`foo/***`:      {ErrAmbiguousStar, 4},
+		`****/foo`:     {ErrAmbiguousStar, 0},
+		`foo**/bar`:    {ErrAmbiguousStar, 3},
+		`foo/**bar`:    {ErrAmbiguousStar, 4},
+		`{a,b**}/c`:    {ErrAmbiguousStar, 4},
+		`src/**/*.go `: {ErrSurroundingWhitespace, 11},
+	}
+	for pattern, expected := range rejected {
+		_, err := Compile(pattern, strict)
+		if !assert.Error(t, err, "Compiling `%s` strictly should fail", pattern) {
+			continue
+		}
+		patternErr := err.(*PatternError)
+		assert.Equal(t, expected.kind, patternErr.Kind, "Unexpected kind for `%s`", pattern)
+		assert.Equal(t, expected.offset, patternErr.Offset, "Unexpected offset for `%s`", pattern)
+
+		// Without strict parsing, the pattern is accepted
+		_, err = Compile(pattern, DefaultOptions)
+		assert.NoError(t, err, "Compiling `%s` non-strictly should succeed", pattern)
+	}
+
+	for _, pattern := range []string{`foo/**`, `**/foo`, `foo/**/bar`, `{**/,}baz`, `foo\\`, `foo\*\*\*`, `*.go`} {
+		_, err := Compile(pattern, strict)
+		assert.NoError(t, err, "Compiling `%s` strictly should succeed", pattern)
+	}
+}
+
 // Benchmark globbing from start to finish; constructing and matching
 func BenchmarkGlobbing(b *testing.B) {
 	pattern := "foo/**/baz/--fo?/*/--baz"
@@ -238,4 +932,128 @@ func BenchmarkGlobbing(b *testing.B) {
 	g, err := Compile(pattern, nil)
 	assert.NoError(b, err)
 	assert.True(b, g.MatchString("foo/bar/bar/baz/--foo/--bar/--baz"))
+
+	// Compare each Glob's own matcher (native or fast path) with its regular expression, over generated paths
+	for _, pattern := range benchmarkPatterns {
+		glob, err := Compile(pattern, nil)
+		assert.NoError(b, err)
+		re := globRegexp(glob)
+		b.Run("glob/"+pattern, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				glob.MatchString(benchmarkPaths[i%len(benchmarkPaths)])
+			}
+		})
+		b.Run("regexp/"+pattern, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				re.MatchString(benchmarkPaths[i%len(benchmarkPaths)])
+			}
+		})
+	}
+}
+
+func TestFindSubmatch(t *testing.T) {
+	extGlobOptions := &Options{
+		Separator:    '/',
+		MatchAtStart: true,
+		MatchAtEnd:   true,
+		ExtGlob:      true,
+	}
+	cases := []struct {
+		pattern  string
+		options  *Options
+		input    string
+		expected []string
+	}{
+		{`services/*/config.yaml`, nil, `services/auth/config.yaml`, []string{`auth`}},
+		{`src/**/*.go`, nil, `src/a/b/c.go`, []string{`a/b`, `c`}},
+		{`src/**/*.go`, nil, `src/c.go`, []string{``, `c`}},
+		{`foo/**`, nil, `foo/a/b`, []string{`a/b`}},
+		{`**/x`, nil, `a/b/x`, []string{`a/b`}},
+		{`v?.[0-9]`, nil, `v1.5`, []string{`1`, `5`}},
+		{`{a,b*}/?`, nil, `bcd/e`, []string{`cd`, `e`}},
+		{`{a*,b*}`, nil, `bcd`, []string{``, `cd`}},
+		{`log-{1..3}/*`, nil, `log-2/x`, []string{`x`}},
+		{`*.go`, nil, `a.txt`, nil},
+		{`*.go`, &Options{Separator: '/'}, `x/a.go/y`, []string{`a`}},
+		{`*/!(*.txt)/?`, extGlobOptions, `a/b.go/c`, []string{`a`, `c`}},
+		{`*/!(*.txt)/?`, extGlobOptions, `a/b.txt/c`, nil},
+		{`*!(x).[ch]`, &Options{Separator: '/', ExtGlob: true}, `foo/ab.c/bar`, []string{`ab`, `c`}},
+	}
+
+	for _, c := range cases {
+		glob, err := Compile(c.pattern, c.options)
+		if !assert.NoError(t, err, "Compiling `%s`", c.pattern) {
+			continue
+		}
+		match := glob.FindStringSubmatch(c.input)
+		if c.expected == nil {
+			assert.Nil(t, match, "`%s` should not match `%s`", c.pattern, c.input)
+			assert.Nil(t, glob.FindSubmatchIndex([]byte(c.input)))
+			continue
+		}
+		if !assert.NotNil(t, match, "`%s` should match `%s`", c.pattern, c.input) {
+			continue
+		}
+		assert.Equal(t, c.expected, match[1:], "Unexpected submatches of `%s` for `%s`", c.pattern, c.input)
+
+		// The other variants agree
+		loc := glob.FindStringSubmatchIndex(c.input)
+		assert.Equal(t, loc, glob.FindSubmatchIndex([]byte(c.input)))
+		bytesMatch := glob.FindSubmatch([]byte(c.input))
+		for i := range match {
+			if loc[2*i] >= 0 {
+				assert.Equal(t, match[i], c.input[loc[2*i]:loc[2*i+1]])
+			}
+			assert.Equal(t, match[i], string(bytesMatch[i]))
+		}
+	}
+
+	// Wildcards which took no part in the match have no indices
+	glob, err := Compile(`a/**/b`, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{0, 3, -1, -1}, glob.FindStringSubmatchIndex(`a/b`))
+}
+
+func TestNamedCaptures(t *testing.T) {
+	glob, err := Compile(`services/{svc:*}/env/{path:**}`, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"svc": "auth", "path": "prod/db.yaml"},
+		glob.Captures(`services/auth/env/prod/db.yaml`))
+	assert.Nil(t, glob.Captures(`services/auth/config.yaml`))
+	// Named captures are also positional submatches
+	assert.Equal(t, []string{`services/auth/env/x`, `auth`, `x`}, glob.FindStringSubmatch(`services/auth/env/x`))
+
+	glob, err = Compile(`{root:**}/*.{go,proto}`, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"root": "a/b"}, glob.Captures(`a/b/c.proto`))
+	assert.Equal(t, map[string]string{"root": ""}, glob.Captures(`c.go`))
+
+	// Consecutive globstars are merged, keeping the name
+	glob, err = Compile(`a/{rest:**}/**`, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"rest": "b/c"}, glob.Captures(`a/b/c`))
+	// ...including when only an expansion of a brace expression places them next to each other
+	glob, err = Compile(`{n:**}/{x,**}/b`, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"n": "p/q"}, glob.Captures(`p/q/b`))
+	assert.Equal(t, map[string]string{"n": "p"}, glob.Captures(`p/x/b`))
+	glob, err = Compile(`{n:**}/{x,{m:**}}/b`, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"n": "p/q", "m": "p/q"}, glob.Captures(`p/q/b`))
+	assert.Equal(t, map[string]string{"n": "p", "m": ""}, glob.Captures(`p/x/b`))
+
+	// Anything else between braces is a brace expression, as before
+	glob, err = Compile(`{a:b,c}*`, nil)
+	assert.NoError(t, err)
+	assert.True(t, glob.MatchString(`a:bx`))
+	assert.Empty(t, glob.Captures(`cx`))
+	glob, err = Compile(`\{a:*}`, nil)
+	assert.NoError(t, err)
+	assert.True(t, glob.MatchString(`{a:xyz}`))
+
+	glob, err = Compile(`!({n:*}.txt)-{m:*}`, &Options{Separator: '/', MatchAtStart: true, MatchAtEnd: true,
+		ExtGlob: true})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"m": "2"}, glob.Captures(`a.go-2`))
+	assert.Nil(t, glob.Captures(`a.txt-2`))
 }
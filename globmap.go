@@ -0,0 +1,145 @@
+package ohmyglob
+
+import (
+	"fmt"
+	"strings"
+)
+
+// GlobMap associates each of an ordered set of Globs with a value, so that strings can be routed to the values of the
+// Globs that match them. As in a GlobSet, later Globs take precedence over earlier ones. A GlobMap is immutable.
+type GlobMap[V any] interface {
+	// Get returns the value of the Glob that takes precedence in matching the string, and true; or, if there is no
+	// such Glob (or it is negative), the zero value and false
+	Get(s string) (V, bool)
+	// GetAll returns the values of all the Globs that match the string (including negative Globs), in order
+	GetAll(s string) []V
+	// Lookup is like Get, but returns the matching Glob and its captures along with the value
+	Lookup(s string) (GlobMapMatch[V], bool)
+	// Globs returns the ordered Globs contained within the map
+	Globs() []Glob
+	// Values returns the values associated with the Globs, in the same order
+	Values() []V
+	// String returns the patterns used to create the GlobMap
+	String() string
+	// WithPrecedence returns a copy of the map which resolves matches by more than one Glob using the given Precedence
+	// (by default, a map uses LastMatch)
+	WithPrecedence(precedence Precedence) GlobMap[V]
+}
+
+// GlobMapEntry is a pattern, and the value associated with it
+type GlobMapEntry[V any] struct {
+	Pattern string
+	Value   V
+}
+
+// GlobMapMatch is the result of looking a string up in a GlobMap
+type GlobMapMatch[V any] struct {
+	// The Glob that took precedence in matching the string
+	Glob  Glob
+	Value V
+	// The text matched by each of the Glob's named captures, by name
+	Captures map[string]string
+}
+
+type globMapImpl[V any] struct {
+	set    *globSetImpl
+	values []V
+}
+
+// NewGlobMap constructs a GlobMap from a slice of Globs, and a slice of the values to associate with them (which must
+// be of the same length)
+func NewGlobMap[V any](globs []Glob, values []V) (GlobMap[V], error) {
+	if len(globs) != len(values) {
+		return nil, fmt.Errorf("%d globs were given with %d values", len(globs), len(values))
+	}
+	set, err := newGlobSet(append([]Glob(nil), globs...))
+	if err != nil {
+		return nil, err
+	}
+	return &globMapImpl[V]{
+		set:    set,
+		values: append([]V(nil), values...),
+	}, nil
+}
+
+// CompileGlobMap constructs a GlobMap from a slice of entries, whose patterns will be compiled individually with the
+// given options. Any error is a *PatternError, whose Index is that of the entry which could not be compiled.
+func CompileGlobMap[V any](entries []GlobMapEntry[V], options *Options) (GlobMap[V], error) {
+	globs := make([]Glob, len(entries))
+	values := make([]V, len(entries))
+	for i, entry := range entries {
+		glob, err := Compile(entry.Pattern, options)
+		if err != nil {
+			if patternErr, ok := err.(*PatternError); ok {
+				patternErr.Index = i
+			}
+			return nil, err
+		}
+		globs[i] = glob
+		values[i] = entry.Value
+	}
+	return NewGlobMap(globs, values)
+}
+
+// Returns the index of the Glob that takes precedence in matching the string, or -1 if there is none (or it is
+// negative)
+func (m *globMapImpl[V]) winner(s string) int {
+	i := m.set.winner(m.set.matchingIndices(s))
+	if i < 0 || m.set.globs[i].IsNegative() {
+		return -1
+	}
+	return i
+}
+
+func (m *globMapImpl[V]) Get(s string) (V, bool) {
+	if i := m.winner(s); i >= 0 {
+		Logger.Tracef("[ohmyglob:GlobMap] %s matched to %s", s, m.set.globs[i].String())
+		return m.values[i], true
+	}
+	var zero V
+	return zero, false
+}
+
+func (m *globMapImpl[V]) GetAll(s string) []V {
+	result := []V(nil)
+	for _, i := range m.set.matchingIndices(s) {
+		result = append(result, m.values[i])
+	}
+	return result
+}
+
+func (m *globMapImpl[V]) Lookup(s string) (GlobMapMatch[V], bool) {
+	i := m.winner(s)
+	if i < 0 {
+		return GlobMapMatch[V]{}, false
+	}
+	glob := m.set.globs[i]
+	return GlobMapMatch[V]{
+		Glob:     glob,
+		Value:    m.values[i],
+		Captures: glob.Captures(s),
+	}, true
+}
+
+func (m *globMapImpl[V]) Globs() []Glob {
+	return m.set.Globs()
+}
+
+func (m *globMapImpl[V]) Values() []V {
+	return append([]V(nil), m.values...)
+}
+
+func (m *globMapImpl[V]) WithPrecedence(precedence Precedence) GlobMap[V] {
+	return &globMapImpl[V]{
+		set:    m.set.WithPrecedence(precedence).(*globSetImpl),
+		values: m.values,
+	}
+}
+
+func (m *globMapImpl[V]) String() string {
+	strs := make([]string, len(m.set.globs))
+	for i, glob := range m.set.globs {
+		strs[i] = glob.String()
+	}
+	return strings.Join(strs, ", ")
+}
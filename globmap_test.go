@@ -0,0 +1,70 @@
+package ohmyglob
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGlobMap(t *testing.T) {
+	owners, err := CompileGlobMap([]GlobMapEntry[string]{
+		{`**`, `@everyone`},
+		{`services/{svc:*}/**`, `@services`},
+		{`services/auth/**`, `@security`},
+		{`!services/auth/docs/**`, `@nobody`},
+		{`**/*.md`, `@docs`},
+	}, DefaultOptions)
+	assert.NoError(t, err)
+	assert.Equal(t, `**, services/{svc:*}/**, services/auth/**, !services/auth/docs/**, **/*.md`, owners.String())
+
+	cases := map[string]string{
+		`README`:                    `@everyone`,
+		`services/billing/main.go`:  `@services`,
+		`services/auth/main.go`:     `@security`,
+		`services/auth/docs/x.txt`:  ``,
+		`services/auth/docs/x.md`:   `@docs`,
+		`services/billing/guide.md`: `@docs`,
+	}
+	for input, expected := range cases {
+		value, ok := owners.Get(input)
+		assert.Equal(t, expected != ``, ok, "Unexpected result for `%s`", input)
+		assert.Equal(t, expected, value, "Unexpected value for `%s`", input)
+	}
+
+	assert.Equal(t, []string{`@everyone`, `@services`, `@security`, `@nobody`},
+		owners.GetAll(`services/auth/docs/x.txt`))
+	assert.Equal(t, []string{`@everyone`}, owners.GetAll(`README`))
+
+	match, ok := owners.Lookup(`services/billing/main.go`)
+	assert.True(t, ok)
+	assert.Equal(t, `@services`, match.Value)
+	assert.Equal(t, `services/{svc:*}/**`, match.Glob.String())
+	assert.Equal(t, map[string]string{"svc": "billing"}, match.Captures)
+	_, ok = owners.Lookup(`services/auth/docs/x.txt`)
+	assert.False(t, ok)
+}
+
+func TestGlobMap_New(t *testing.T) {
+	glob1, _ := Compile(`*.go`, nil)
+	glob2, _ := Compile(`*_test.go`, nil)
+	handlers, err := NewGlobMap([]Glob{glob1, glob2}, []int{1, 2})
+	assert.NoError(t, err)
+	assert.Equal(t, []Glob{glob1, glob2}, handlers.Globs())
+	assert.Equal(t, []int{1, 2}, handlers.Values())
+	value, ok := handlers.Get(`x_test.go`)
+	assert.True(t, ok)
+	assert.Equal(t, 2, value)
+	value, ok = handlers.Get(`x.txt`)
+	assert.False(t, ok)
+	assert.Equal(t, 0, value)
+
+	_, err = NewGlobMap([]Glob{glob1}, []int{1, 2})
+	assert.Error(t, err)
+
+	_, err = CompileGlobMap([]GlobMapEntry[int]{{`a`, 1}, {`[b-a]`, 2}}, nil)
+	var patternErr *PatternError
+	if assert.True(t, errors.As(err, &patternErr)) {
+		assert.Equal(t, 1, patternErr.Index)
+	}
+}
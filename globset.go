@@ -1,12 +1,17 @@
 package ohmyglob
 
 import (
+	"errors"
 	"io"
+	"regexp"
+	"sort"
 	"strings"
 )
 
 // GlobSet represents an ordered set of Globs, and has the same matching capabilities as a Glob. Globbing is done
-// in order, with later globs taking precedence over earlier globs in the set. A GlobSet is immutable.
+// in order, with later globs taking precedence over earlier globs in the set (unless the set is given MostSpecific
+// precedence). Each Glob matches according to the Options it was compiled with, so a set may freely mix (for example)
+// case-sensitive and case-insensitive globs. A GlobSet is immutable.
 type GlobSet interface {
 	GlobMatcher
 	// Globs returns the ordered Glob objects contained within the set
@@ -19,94 +24,223 @@ type GlobSet interface {
 	// AllMatchingGlobs returns all Globs that match the specified pattern (or do not match, in the case of a negative
 	// glob)
 	AllMatchingGlobs(b []byte) []Glob
+	// MatchReaderErr is like MatchReader, but returns any error encountered while reading (including ErrInputTooLong
+	// if the input is longer than the set's maximum reader length), rather than reporting that the input does not match
+	MatchReaderErr(r io.RuneReader) (bool, error)
+	// CouldMatchPrefix reports whether the set could match any path beneath the directory dir (ie. any string
+	// beginning with dir followed by the separator), taking negative Globs into account. It may report true even if
+	// no such path matches, but never reports false if one does.
+	CouldMatchPrefix(dir string) bool
+	// WithPrecedence returns a copy of the set which resolves matches by more than one Glob using the given Precedence
+	// (by default, a set uses LastMatch)
+	WithPrecedence(precedence Precedence) GlobSet
+	// WithMaxReaderLength returns a copy of the set which buffers at most maxLength bytes from a RuneReader in order to
+	// match it (by default, or if maxLength is 0, there is no limit). A GlobSet must read the whole input before it can
+	// decide whether it matches, so setting a limit prevents untrusted readers from forcing unbounded buffering.
+	WithMaxReaderLength(maxLength int) GlobSet
 }
 
-type globSetImpl []Glob
+// ErrInputTooLong is returned by MatchReaderErr when the input is longer than the set's maximum reader length
+var ErrInputTooLong = errors.New("input is longer than the maximum reader length")
 
-func (g globSetImpl) String() string {
-	strs := make([]string, len(g))
-	for i, glob := range g {
+type globSetImpl struct {
+	globs      []Glob
+	precedence Precedence
+	// The maximum number of bytes buffered by MatchReaderErr, or 0 for no limit
+	maxReaderLength int
+	// The specificity score of each member (only calculated for sets with MostSpecific precedence)
+	specificity []int64
+	// Finds the required literals of the members which have them; these members are only matched if their literal
+	// appears in the input
+	literals *literalIndex
+	// The indices of the members with each literal
+	literalGlobs [][]int
+	// Simulates the regular expressions of the members which implement automatable, in a single pass
+	automaton *setAutomaton
+	// The index within globs of each of the automaton's programs, and whether the program's matches must be verified
+	automatonGlobs []int
+	verify         []bool
+	// The indices of the members which must be matched individually
+	individual []int
+	// Answers CouldMatchPrefix queries
+	prefix *prefixMatcher
+}
+
+func newGlobSet(globs []Glob) (*globSetImpl, error) {
+	set := &globSetImpl{
+		globs:  globs,
+		prefix: &prefixMatcher{},
+	}
+	res := make([]*regexp.Regexp, 0, len(globs))
+	literals := make([]string, 0, len(globs))
+	literalIds := make(map[string]int, len(globs))
+	for i, glob := range globs {
+		if p, ok := glob.(prefilterable); ok && len(p.requiredLiteral()) >= MinPrefilterLiteralLength {
+			literal := p.requiredLiteral()
+			id, ok := literalIds[literal]
+			if !ok {
+				id = len(literals)
+				literalIds[literal] = id
+				literals = append(literals, literal)
+				set.literalGlobs = append(set.literalGlobs, nil)
+			}
+			set.literalGlobs[id] = append(set.literalGlobs[id], i)
+		} else if a, ok := glob.(automatable); ok {
+			re, exact := a.automatonRegexp()
+			res = append(res, re)
+			set.automatonGlobs = append(set.automatonGlobs, i)
+			set.verify = append(set.verify, !exact)
+		} else {
+			set.individual = append(set.individual, i)
+		}
+	}
+
+	if len(literals) > 0 {
+		set.literals = newLiteralIndex(literals)
+	}
+	if len(res) > 0 {
+		automaton, err := newSetAutomaton(res)
+		if err != nil {
+			return nil, err
+		}
+		set.automaton = automaton
+	}
+	return set, nil
+}
+
+func (g *globSetImpl) String() string {
+	strs := make([]string, len(g.globs))
+	for i, glob := range g.globs {
 		strs[i] = glob.String()
 	}
 	return strings.Join(strs, ", ")
 }
 
-func (g globSetImpl) Globs() []Glob {
-	globs := make([]Glob, 0, len(g))
-	globs = append(globs, g...)
+func (g *globSetImpl) Globs() []Glob {
+	globs := make([]Glob, 0, len(g.globs))
+	globs = append(globs, g.globs...)
 	return globs
 }
 
-func (g globSetImpl) MatchingGlob(b []byte) Glob {
-	// By iterating in reverse order, we can bail early if we get a match further down. If we iterated in normal order,
-	// we would HAVE to check every glob
-	for i := len(g) - 1; i >= 0; i-- {
-		glob := g[i]
-		matches := glob.Match(b)
-		if matches {
-			Logger.Tracef("[ohmyglob:GlobSet] %s matched to %s", string(b), glob.String())
-			return glob
+// matchingIndices returns the (ascending) indices of the Globs in the set which match the input
+func (g *globSetImpl) matchingIndices(s string) []int {
+	var result []int
+	if g.automaton != nil {
+		for _, i := range g.automaton.match(s) {
+			globIdx := g.automatonGlobs[i]
+			if !g.verify[i] || g.globs[globIdx].MatchString(s) {
+				result = append(result, globIdx)
+			}
+		}
+	}
+
+	sorted := true
+	if g.literals != nil {
+		for _, literal := range g.literals.find(s) {
+			for _, globIdx := range g.literalGlobs[literal] {
+				if g.globs[globIdx].MatchString(s) {
+					result = append(result, globIdx)
+					sorted = false
+				}
+			}
+		}
+	}
+	for _, globIdx := range g.individual {
+		if g.globs[globIdx].MatchString(s) {
+			result = append(result, globIdx)
+			sorted = false
 		}
 	}
+	if !sorted {
+		sort.Ints(result)
+	}
+	return result
+}
+
+func (g *globSetImpl) MatchingGlob(b []byte) Glob {
+	if i := g.winner(g.matchingIndices(string(b))); i >= 0 {
+		glob := g.globs[i]
+		Logger.Tracef("[ohmyglob:GlobSet] %s matched to %s", string(b), glob.String())
+		return glob
+	}
 
 	return nil
 }
 
-func (g globSetImpl) AllMatchingGlobs(b []byte) []Glob {
+func (g *globSetImpl) AllMatchingGlobs(b []byte) []Glob {
 	result := []Glob(nil)
-	for _, glob := range g {
-		if glob.Match(b) {
-			Logger.Tracef("[ohmyglob:GlobSet] %s matched to %s", string(b), glob.String())
-			result = append(result, glob)
-		}
+	for _, i := range g.matchingIndices(string(b)) {
+		Logger.Tracef("[ohmyglob:GlobSet] %s matched to %s", string(b), g.globs[i].String())
+		result = append(result, g.globs[i])
 	}
 	return result
 }
 
-func (g globSetImpl) Match(b []byte) bool {
+func (g *globSetImpl) WithPrecedence(precedence Precedence) GlobSet {
+	set := *g
+	set.precedence = precedence
+	if precedence == MostSpecific && set.specificity == nil {
+		set.specificity = make([]int64, len(g.globs))
+		for i, glob := range g.globs {
+			set.specificity[i] = glob.Specificity()
+		}
+	}
+	return &set
+}
+
+func (g *globSetImpl) WithMaxReaderLength(maxLength int) GlobSet {
+	set := *g
+	set.maxReaderLength = maxLength
+	return &set
+}
+
+func (g *globSetImpl) Match(b []byte) bool {
 	glob := g.MatchingGlob(b)
 	return glob != nil && !glob.IsNegative()
 }
 
-func (g globSetImpl) MatchReader(r io.RuneReader) bool {
-	// Drain the reader and convert to a byte array
-	b := make([]byte, 0, 10)
-	for {
-		rn, _, err := r.ReadRune()
-		if err == io.EOF {
-			break
-		} else if err != nil {
-			return false
-		}
-		b = append(b, byte(rn))
-	}
+func (g *globSetImpl) MatchReader(r io.RuneReader) bool {
+	matches, err := g.MatchReaderErr(r)
+	return err == nil && matches
+}
 
-	return g.Match(b)
+func (g *globSetImpl) MatchReaderErr(r io.RuneReader) (bool, error) {
+	s, err := readAll(r, g.maxReaderLength)
+	if err != nil {
+		return false, err
+	}
+	return g.MatchString(s), nil
 }
 
-func (g globSetImpl) MatchString(s string) bool {
+func (g *globSetImpl) MatchString(s string) bool {
 	return g.Match([]byte(s))
 }
 
-// NewGlobSet constructs a GlobSet from a slice of Globs.
+// NewGlobSet constructs a GlobSet from a slice of Globs. Globs which require a literal (eg. an extension or a directory
+// name) to appear in the input are indexed by it, and only matched against input in which it is found; the regular
+// expressions of the remaining Globs are combined into a single automaton, so the set is matched in one pass over the
+// input (Globs implemented outside this package are matched individually).
 func NewGlobSet(globs []Glob) (GlobSet, error) {
-	set := make(globSetImpl, len(globs))
+	set := make([]Glob, len(globs))
 	for i, glob := range globs {
 		set[i] = glob
 	}
-	return set, nil
+	return newGlobSet(set)
 }
 
-// CompileGlobSet constructs a GlobSet from a slice of strings, which will be compiled individually to Globs.
+// CompileGlobSet constructs a GlobSet from a slice of strings, which will be compiled individually to Globs. Any error
+// is a *PatternError, whose Index is that of the pattern which could not be compiled.
 func CompileGlobSet(patterns []string, options *Options) (GlobSet, error) {
-	globs := make(globSetImpl, len(patterns))
+	globs := make([]Glob, len(patterns))
 	for i, pattern := range patterns {
 		glob, err := Compile(pattern, options)
 		if err != nil {
-			return nil, err
+			patternErr := err.(*PatternError)
+			patternErr.Index = i
+			return nil, patternErr
 		}
 		globs[i] = glob
 	}
 
-	return globs, nil
+	return newGlobSet(globs)
 }
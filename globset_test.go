@@ -1,6 +1,10 @@
 package ohmyglob
 
 import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
 	"strings"
 	"testing"
 
@@ -77,6 +81,84 @@ func TestGlobSet_MatchReader(t *testing.T) {
 	assert.False(t, set.MatchReader(matchReader), "(%s) should not match %s", set.String(), match)
 }
 
+// errReader yields its text, then fails
+type errReader struct {
+	reader *strings.Reader
+}
+
+func (r errReader) ReadRune() (rune, int, error) {
+	if r.reader.Len() == 0 {
+		return 0, 0, io.ErrUnexpectedEOF
+	}
+	return r.reader.ReadRune()
+}
+
+func TestGlobSet_MatchReaderUnicode(t *testing.T) {
+	set, err := CompileGlobSet([]string{"foo/*/b?r", "!foo/∆*/bar", "ƒoo/**"}, DefaultOptions)
+	assert.NoError(t, err)
+
+	inputs := []string{"foo/baz∆˙¨®˙¨¥ƒ®†˙ƒ†¨®†√˙/bar", "foo/baz/b∆r", "foo/∆/bar", "ƒoo/bar", "foo/bar"}
+	for _, input := range inputs {
+		assert.Equal(t, set.MatchString(input), set.MatchReader(strings.NewReader(input)),
+			"MatchReader should agree with MatchString for %s", input)
+		matches, err := set.MatchReaderErr(strings.NewReader(input))
+		assert.NoError(t, err)
+		assert.Equal(t, set.MatchString(input), matches)
+	}
+
+	matches, err := set.MatchReaderErr(errReader{strings.NewReader("ƒoo/bar")})
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+	assert.False(t, matches)
+	assert.False(t, set.MatchReader(errReader{strings.NewReader("ƒoo/bar")}))
+}
+
+func TestGlobSet_MatchReaderInvalidUTF8(t *testing.T) {
+	set, err := CompileGlobSet([]string{"foo/\uFFFD", "bar/?", "baz/*z"}, DefaultOptions)
+	assert.NoError(t, err)
+
+	inputs := []string{"foo/\xff", "foo/\uFFFD", "bar/\xff", "baz/\xffz", "baz/\uFFFDz"}
+	for _, input := range inputs {
+		assert.Equal(t, set.MatchString(input), set.MatchReader(strings.NewReader(input)),
+			"MatchReader should agree with MatchString for %q", input)
+		assert.Equal(t, set.MatchString(input), set.MatchReader(bytes.NewReader([]byte(input))),
+			"MatchReader should agree with MatchString for %q", input)
+	}
+}
+
+func TestGlobSet_WithMaxReaderLength(t *testing.T) {
+	unbounded, err := CompileGlobSet([]string{"ƒoo/**"}, DefaultOptions)
+	assert.NoError(t, err)
+	set := unbounded.WithMaxReaderLength(8)
+
+	matches, err := set.MatchReaderErr(strings.NewReader("ƒoo/bar"))
+	assert.NoError(t, err)
+	assert.True(t, matches)
+	matches, err = set.MatchReaderErr(errReader{strings.NewReader("ƒoo/bar")})
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+	assert.False(t, matches)
+
+	readers := []io.RuneReader{strings.NewReader("ƒoo/barr"), onlyRuneReader{strings.NewReader("ƒoo/barr")}}
+	for _, reader := range readers {
+		matches, err = set.MatchReaderErr(reader)
+		assert.Equal(t, ErrInputTooLong, err)
+		assert.False(t, matches)
+	}
+	assert.False(t, set.MatchReader(strings.NewReader("ƒoo/barr")))
+
+	matches, err = unbounded.MatchReaderErr(strings.NewReader("ƒoo/barr"))
+	assert.NoError(t, err, "the original set should not be limited")
+	assert.True(t, matches)
+}
+
+// onlyRuneReader hides every method of its reader except ReadRune
+type onlyRuneReader struct {
+	reader io.RuneReader
+}
+
+func (r onlyRuneReader) ReadRune() (rune, int, error) {
+	return r.reader.ReadRune()
+}
+
 func TestGlobSet_AllMatchingGlobs(t *testing.T) {
 	patterns := []string{
 		"foo/**/baz",
@@ -98,3 +180,153 @@ func TestGlobSet_AllMatchingGlobs(t *testing.T) {
 	match = []byte("foo/yes/baz/foo/123/baz")
 	assert.Len(t, set.AllMatchingGlobs(match), 2)
 }
+
+func TestGlobSet_MixedOptions(t *testing.T) {
+	insensitive := &Options{
+		Separator:    '/',
+		MatchAtStart: true,
+		MatchAtEnd:   true,
+		CaseFolding:  FoldASCII,
+	}
+	// Each glob matches according to the options it was compiled with
+	globs := make([]Glob, 3)
+	var err error
+	globs[0], err = Compile("static/**", insensitive)
+	assert.NoError(t, err)
+	globs[1], err = Compile("!static/*.tmp", DefaultOptions)
+	assert.NoError(t, err)
+	globs[2], err = Compile("static/KEEP.tmp", DefaultOptions)
+	assert.NoError(t, err)
+	set, err := NewGlobSet(globs)
+	assert.NoError(t, err)
+
+	for _, match := range []string{"static/a.css", "STATIC/a.css", "Static/a.TMP", "static/KEEP.tmp"} {
+		assert.True(t, set.MatchString(match), "(%s) should match %s", set.String(), match)
+	}
+	for _, match := range []string{"static/a.tmp", "static/keep.tmp", "assets/a.css"} {
+		assert.False(t, set.MatchString(match), "(%s) should not match %s", set.String(), match)
+	}
+}
+
+// userGlob is a Glob implemented outside the package, which a GlobSet must match individually
+type userGlob struct {
+	suffix string
+}
+
+func (g userGlob) Match(b []byte) bool              { return g.MatchString(string(b)) }
+func (g userGlob) MatchReader(r io.RuneReader) bool { return false }
+func (g userGlob) MatchString(s string) bool        { return strings.HasSuffix(s, g.suffix) }
+func (g userGlob) String() string                   { return "*" + g.suffix }
+func (g userGlob) IsNegative() bool                 { return false }
+
+func (g userGlob) FindStringSubmatch(s string) []string   { return nil }
+func (g userGlob) FindSubmatch(b []byte) [][]byte         { return nil }
+func (g userGlob) FindStringSubmatchIndex(s string) []int { return nil }
+func (g userGlob) FindSubmatchIndex(b []byte) []int       { return nil }
+func (g userGlob) Captures(s string) map[string]string    { return nil }
+func (g userGlob) Specificity() int64                     { return 0 }
+func (g userGlob) CouldMatchPrefix(dir string) bool       { return true }
+
+// Compares the globs matched by a GlobSet (in a single pass) with those matched by each glob individually
+func TestGlobSet_Automaton(t *testing.T) {
+	// Prefiltering is disabled, so that every glob is simulated by the automaton
+	defer func(min int) { MinPrefilterLiteralLength = min }(MinPrefilterLiteralLength)
+	MinPrefilterLiteralLength = math.MaxInt32
+
+	optionSets := []*Options{
+		DefaultOptions,
+		&Options{Separator: '/', MatchAtStart: false, MatchAtEnd: true},
+		&Options{Separator: '/', MatchAtStart: true, MatchAtEnd: false},
+		&Options{Separator: '/'},
+		&Options{Separator: '/', MatchAtStart: true, MatchAtEnd: true, CaseFolding: FoldUnicode},
+		&Options{Separator: '/', MatchAtStart: true, MatchAtEnd: true, ExtGlob: true},
+	}
+	patterns := []string{`foo/**`, `!foo/bar`, `**/*.go`, `*.[ch]`, `a{b,c}d`, `ÄB/?`, `!(*.go)`, `**/x/**`,
+		`foo/bar`, `log-{1..3}`, `!**/vendor/**`}
+	globs := make([]Glob, 0, len(optionSets)*len(patterns)+1)
+	for _, options := range optionSets {
+		for _, pattern := range patterns {
+			glob, err := Compile(pattern, options)
+			if assert.NoError(t, err, "Compiling `%s`", pattern) {
+				globs = append(globs, glob)
+			}
+		}
+	}
+	globs = append(globs, userGlob{".txt"})
+	set, err := NewGlobSet(globs)
+	assert.NoError(t, err)
+
+	inputs := []string{``, `foo`, `foo/bar`, `foo/bar/baz.go`, `a.go`, `x.c`, `abd`, `acd`, `äb/z`, `ÄB/z`,
+		`a/x/b`, `log-2`, `log-4`, `vendor/a.txt`, `a/vendor/b`, `zfoo/barz`, "foo/a\nb.go", `∆/x/∆`}
+	for _, input := range inputs {
+		var expected []Glob
+		for _, glob := range globs {
+			if glob.MatchString(input) {
+				expected = append(expected, glob)
+			}
+		}
+		assert.Equal(t, expected, set.AllMatchingGlobs([]byte(input)), "Unexpected matches for `%s`", input)
+
+		if len(expected) > 0 {
+			assert.Equal(t, expected[len(expected)-1], set.MatchingGlob([]byte(input)))
+		} else {
+			assert.Nil(t, set.MatchingGlob([]byte(input)))
+		}
+	}
+}
+
+// Builds a large set of rules resembling those in a typical ignore or ownership file
+func benchmarkGlobSet(b *testing.B, size int) (GlobSet, []Glob) {
+	patterns := make([]string, size)
+	for i := range patterns {
+		switch i % 4 {
+		case 0:
+			patterns[i] = fmt.Sprintf("src/dir%d/**", i)
+		case 1:
+			patterns[i] = fmt.Sprintf("**/*.ext%d", i)
+		case 2:
+			patterns[i] = fmt.Sprintf("!src/dir%d/*.go", i)
+		case 3:
+			patterns[i] = fmt.Sprintf("pkg/*/file%d.{go,proto}", i)
+		}
+	}
+	set, err := CompileGlobSet(patterns, DefaultOptions)
+	assert.NoError(b, err)
+	return set, set.Globs()
+}
+
+func BenchmarkGlobSet_MatchingGlob(b *testing.B) {
+	set, _ := benchmarkGlobSet(b, 5000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		set.MatchingGlob([]byte(benchmarkPaths[i%len(benchmarkPaths)]))
+	}
+}
+
+// Matches each glob in turn, as a GlobSet did before combining its globs into a single automaton
+func BenchmarkGlobSet_MatchingGlobIndividually(b *testing.B) {
+	_, globs := benchmarkGlobSet(b, 5000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		path := []byte(benchmarkPaths[i%len(benchmarkPaths)])
+		for j := len(globs) - 1; j >= 0; j-- {
+			if globs[j].Match(path) {
+				break
+			}
+		}
+	}
+}
+
+func TestGlobSet_AutomatonCacheReset(t *testing.T) {
+	defer func(max int) { MaxAutomatonStates = max }(MaxAutomatonStates)
+	MaxAutomatonStates = 2
+
+	set, err := CompileGlobSet([]string{`**/*.go`, `!vendor/**`, `a?c/**`}, DefaultOptions)
+	assert.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		assert.True(t, set.MatchString(`src/foo.go`))
+		assert.False(t, set.MatchString(`vendor/foo.go`))
+		assert.True(t, set.MatchString(`abc/d`))
+		assert.False(t, set.MatchString(`abcd`))
+	}
+}
@@ -0,0 +1,40 @@
+// Package equivalence generates the random patterns and inputs with which ohmyglob's tests compare a matcher against a
+// reference implementation.
+package equivalence
+
+import (
+	"math/rand"
+	"strings"
+)
+
+// Parts describes a random string: the concatenation of between Min and Max randomly chosen Parts, joined by Separator
+type Parts struct {
+	Parts     []string
+	Min       int
+	Max       int
+	Separator string
+}
+
+// Check generates 2000 random patterns, and calls test with each. If test returns a function (rather than skipping the
+// pattern by returning nil), it is called with 50 random inputs. The same patterns and inputs are generated on every
+// run, so that failures are reproducible.
+func Check(patterns, inputs Parts, test func(pattern string) func(input string)) {
+	random := rand.New(rand.NewSource(1))
+	randomString := func(parts Parts) string {
+		strs := make([]string, parts.Min+random.Intn(parts.Max-parts.Min+1))
+		for i := range strs {
+			strs[i] = parts.Parts[random.Intn(len(parts.Parts))]
+		}
+		return strings.Join(strs, parts.Separator)
+	}
+
+	for i := 0; i < 2000; i++ {
+		match := test(randomString(patterns))
+		if match == nil {
+			continue
+		}
+		for j := 0; j < 50; j++ {
+			match(randomString(inputs))
+		}
+	}
+}
@@ -0,0 +1,250 @@
+package ohmyglob
+
+import (
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// segmentElement is a single element of a path segment pattern: a run of literal text, a ?, a *, or a bracket
+// expression
+type segmentElement struct {
+	elementType tc
+	literal     string
+	class       *charClass
+}
+
+// segmentPattern matches a single path segment (the text between two separators), or, if it is a globstar, any number
+// of whole segments
+type segmentPattern struct {
+	globStar bool
+	elements []segmentElement
+}
+
+// nativeGlob is a Glob which is matched segment by segment, without the overhead of a regular expression. It is used
+// for patterns consisting only of literals, wildcards, bracket expressions and whole-segment globstars; the embedded
+// globImpl's regular expression is equivalent, and is kept as a fallback.
+type nativeGlob struct {
+	*globImpl
+	segments  []segmentPattern
+	separator rune
+	// Set to true if the pattern is a lone globstar, which matches anything without a newline (even the empty string)
+	matchAll bool
+	// Set to true if the pattern contains any globstars
+	hasGlobStar bool
+}
+
+// newNativeGlob returns a nativeGlob for the (negation-free) pattern body, or nil if the pattern can't be matched
+// natively
+func newNativeGlob(glob *globImpl, body string) *nativeGlob {
+	options := glob.parserState.options
+	if !options.MatchAtStart || !options.MatchAtEnd || options.CaseFolding != CaseSensitive ||
+		options.AltSeparator != 0 || options.VolumeRoots {
+		return nil
+	}
+
+	g := &nativeGlob{
+		globImpl:  glob,
+		segments:  make([]segmentPattern, 0, 4),
+		separator: options.Separator,
+	}
+	current := segmentPattern{}
+	lastTokenType := tcUnknown
+	tokeniser := newGlobTokeniser(strings.NewReader(body), options)
+	for tokeniser.Scan() {
+		token, tokenType := tokeniser.Token()
+		switch tokenType {
+		case tcLiteral:
+			if strings.ContainsRune(token, g.separator) {
+				// An escaped separator is matched literally, spanning two segments
+				return nil
+			}
+			// Consecutive literals (split by escapers) are merged
+			if n := len(current.elements); n > 0 && current.elements[n-1].elementType == tcLiteral {
+				current.elements[n-1].literal += token
+			} else {
+				current.elements = append(current.elements, segmentElement{elementType: tcLiteral, literal: token})
+			}
+		case tcStar, tcAny:
+			current.elements = append(current.elements, segmentElement{elementType: tokenType})
+		case tcCharClass:
+			class, err := parseCharClass(token, 0, options.escaper())
+			if err != nil {
+				return nil
+			}
+			current.elements = append(current.elements, segmentElement{elementType: tokenType, class: class})
+		case tcSeparator:
+			g.segments = append(g.segments, current)
+			current = segmentPattern{}
+		case tcGlobStar:
+			// Only globstars which are whole segments (and which are not followed by a trailing separator) are
+			// supported
+			if lastTokenType != tcUnknown && lastTokenType != tcSeparator {
+				return nil
+			}
+			if tokeniser.Peek() {
+				if _, peekedType := tokeniser.PeekToken(); peekedType != tcSeparator {
+					return nil
+				}
+				tokeniser.Scan()
+				if !tokeniser.Peek() {
+					return nil
+				}
+				tokenType = tcSeparator
+			}
+			// Consecutive globstars are equivalent to one
+			if n := len(g.segments); n == 0 || !g.segments[n-1].globStar {
+				g.segments = append(g.segments, segmentPattern{globStar: true})
+			}
+			current = segmentPattern{}
+			lastTokenType = tokenType
+			continue
+		default:
+			return nil
+		}
+		lastTokenType = tokenType
+	}
+	if tokeniser.Err() != nil {
+		return nil
+	}
+	if lastTokenType != tcGlobStar {
+		g.segments = append(g.segments, current)
+	}
+	if len(g.segments) == 2 && !g.segments[0].globStar && len(g.segments[0].elements) == 0 && g.segments[1].globStar {
+		// The regular expression for "/**" matches anything, as the separator is removed from before the trailing
+		// globstar; the regular expression is used to preserve this behaviour
+		return nil
+	}
+
+	for _, segment := range g.segments {
+		g.hasGlobStar = g.hasGlobStar || segment.globStar
+	}
+	g.matchAll = len(g.segments) == 1 && g.segments[0].globStar
+	return g
+}
+
+// Returns whether the input contains an empty segment
+func (g *nativeGlob) hasEmptySegment(s string) bool {
+	sep := string(g.separator)
+	return s == "" || strings.HasPrefix(s, sep) || strings.HasSuffix(s, sep) || strings.Contains(s, sep+sep)
+}
+
+// Returns the end of the input segment starting at pos, and the start of the following segment (which is past the end
+// of the input if there are no more segments)
+func (g *nativeGlob) nextSegment(s string, pos int) (int, int) {
+	if idx := strings.IndexRune(s[pos:], g.separator); idx >= 0 {
+		return pos + idx, pos + idx + utf8.RuneLen(g.separator)
+	}
+	return len(s), len(s) + 1
+}
+
+func (g *nativeGlob) MatchString(s string) bool {
+	if g.matchAll {
+		return strings.IndexByte(s, '\n') < 0
+	} else if g.hasGlobStar && (g.hasEmptySegment(s) || strings.IndexByte(s, '\n') >= 0) {
+		// A globstar can't match a lone empty segment (as its regular expression requires at least one character), or a
+		// newline, so such input can't be matched greedily
+		return g.globImpl.MatchString(s)
+	}
+
+	// The segments are matched like the runes of a wildcard pattern, with globstars acting as stars. Only the most
+	// recent globstar ever needs to be revisited, to absorb one more segment.
+	segIdx, pos := 0, 0
+	starIdx, starPos := -1, 0
+	for pos <= len(s) {
+		if segIdx < len(g.segments) {
+			segment := &g.segments[segIdx]
+			if segment.globStar {
+				starIdx, starPos = segIdx, pos
+				segIdx++
+				continue
+			}
+			end, next := g.nextSegment(s, pos)
+			if matchSegment(segment.elements, s[pos:end]) {
+				segIdx++
+				pos = next
+				continue
+			}
+		}
+		if starIdx < 0 {
+			return false
+		}
+
+		// Backtrack, with the globstar absorbing another segment
+		_, starPos = g.nextSegment(s, starPos)
+		segIdx, pos = starIdx+1, starPos
+	}
+
+	for segIdx < len(g.segments) && g.segments[segIdx].globStar {
+		segIdx++
+	}
+	return segIdx == len(g.segments)
+}
+
+func (g *nativeGlob) Match(b []byte) bool {
+	return g.MatchString(string(b))
+}
+
+func (g *nativeGlob) MatchReader(r io.RuneReader) bool {
+	s, err := readAll(r, 0)
+	return err == nil && g.MatchString(s)
+}
+
+// matchSegment reports whether the elements match the whole of a single segment. Stars are handled greedily, only ever
+// revisiting the most recent star, so matching takes at worst O(len(elements) * len(s)) time.
+func matchSegment(elements []segmentElement, s string) bool {
+	elemIdx, pos := 0, 0
+	starIdx, starPos := -1, 0
+	for pos < len(s) || elemIdx < len(elements) {
+		if elemIdx < len(elements) {
+			element := &elements[elemIdx]
+			switch element.elementType {
+			case tcStar:
+				starIdx, starPos = elemIdx, pos
+				elemIdx++
+				continue
+			case tcLiteral:
+				if strings.HasPrefix(s[pos:], element.literal) {
+					elemIdx++
+					pos += len(element.literal)
+					continue
+				}
+			case tcAny:
+				if pos < len(s) {
+					_, width := utf8.DecodeRuneInString(s[pos:])
+					elemIdx++
+					pos += width
+					continue
+				}
+			case tcCharClass:
+				if pos < len(s) {
+					r, width := utf8.DecodeRuneInString(s[pos:])
+					if element.class.contains(r) {
+						elemIdx++
+						pos += width
+						continue
+					}
+				}
+			}
+		}
+		if starIdx < 0 || starPos >= len(s) {
+			return false
+		}
+
+		// Backtrack, with the star absorbing another rune
+		_, width := utf8.DecodeRuneInString(s[starPos:])
+		starPos += width
+		elemIdx, pos = starIdx+1, starPos
+	}
+	return true
+}
+
+// contains reports whether the rune is matched by the class (ignoring the separator, which is never matched)
+func (c *charClass) contains(r rune) bool {
+	i := sort.Search(len(c.ranges), func(i int) bool {
+		return c.ranges[i].hi >= r
+	})
+	found := i < len(c.ranges) && c.ranges[i].lo <= r
+	return found != c.negated
+}
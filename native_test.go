@@ -0,0 +1,90 @@
+package ohmyglob
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/obeattie/ohmyglob/internal/equivalence"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNativeGlob_Used(t *testing.T) {
+	native := []string{`*.go`, `foo/**/bar`, `**/*.[ch]`, `src/*/**`, `a/?/[!x]*`, `**`, `!foo/*`, `foo\*/*`, `a//b/*`}
+	for _, pattern := range native {
+		glob, err := Compile(pattern, DefaultOptions)
+		if assert.NoError(t, err) {
+			assert.IsType(t, &nativeGlob{}, glob, "`%s` should be matched natively", pattern)
+		}
+	}
+
+	regexp := []string{`{a,b}`, `foo**/x`, `**bar/*`, `**/`, `foo\/*`, `/**`}
+	for _, pattern := range regexp {
+		glob, err := Compile(pattern, DefaultOptions)
+		if assert.NoError(t, err) {
+			assert.IsType(t, &globImpl{}, glob, "`%s` should not be matched natively", pattern)
+		}
+	}
+
+	glob, err := Compile(`*.go`, &Options{Separator: '/', MatchAtStart: true})
+	if assert.NoError(t, err) {
+		assert.IsType(t, &globImpl{}, glob, "Partial matches should not be matched natively")
+	}
+}
+
+// Compares the results of native matching with those of the equivalent regular expression, for random patterns and
+// inputs
+func TestNativeGlob_Equivalence(t *testing.T) {
+	patternParts := []string{`a`, `b`, `ab`, `*`, `?`, `/`, `/`, `**`, `[ab]`, `[!a]`, `\*`, `ä`}
+	inputParts := []string{`a`, `b`, `ab`, `/`, `/`, `*`, `ä`, `c`}
+	equivalence.Check(equivalence.Parts{Parts: patternParts, Max: 6}, equivalence.Parts{Parts: inputParts, Max: 8},
+		func(pattern string) func(string) {
+			glob, err := Compile(pattern, DefaultOptions)
+			if err != nil {
+				return nil
+			}
+			native, ok := glob.(*nativeGlob)
+			if !ok {
+				return nil
+			}
+			return func(input string) {
+				expected := native.globImpl.MatchString(input)
+				assert.Equal(t, expected, native.MatchString(input), "Glob `%s` should match `%s`: %v (regex `%s`)",
+					pattern, input, expected, native.Regexp.String())
+			}
+		})
+}
+
+func TestNativeGlob_CustomSeparator(t *testing.T) {
+	glob, err := Compile(`**ف?*.go`, &Options{
+		Separator:    'ف',
+		MatchAtStart: true,
+		MatchAtEnd:   true,
+	})
+	if assert.NoError(t, err) {
+		assert.IsType(t, &nativeGlob{}, glob)
+		assert.True(t, glob.MatchString(`aفbفc.go`))
+		assert.True(t, glob.MatchString(`c.go`))
+		assert.False(t, glob.MatchString(`فc.go`))
+		assert.False(t, glob.MatchString(`a/c.g`))
+	}
+}
+
+// Paths resembling those in a large source tree, for matching benchmarks
+var benchmarkPaths = func() []string {
+	dirs := []string{"src", "pkg", "vendor", "internal", "cmd", "test", "docs", "build"}
+	exts := []string{".go", ".proto", ".md", ".txt", "_test.go", ".c", ".h"}
+	random := rand.New(rand.NewSource(1))
+	paths := make([]string, 1000)
+	for i := range paths {
+		parts := make([]string, 1+random.Intn(6))
+		for j := range parts {
+			parts[j] = dirs[random.Intn(len(dirs))]
+		}
+		parts[len(parts)-1] += exts[random.Intn(len(exts))]
+		paths[i] = strings.Join(parts, "/")
+	}
+	return paths
+}()
+
+var benchmarkPatterns = []string{`*.go`, `src/**/*_test.go`, `**/vendor/**`, `foo/**/baz/--fo?/*/--baz`}
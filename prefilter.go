@@ -0,0 +1,163 @@
+package ohmyglob
+
+import (
+	"sort"
+	"strings"
+)
+
+// MinPrefilterLiteralLength is the minimum length (in bytes) of a required literal for a Glob in a GlobSet to be
+// prefiltered by it. Shorter literals appear in too much input to be useful filters.
+var MinPrefilterLiteralLength = 2
+
+// prefilterable is implemented by Globs which can report a literal that must appear in any input they match
+type prefilterable interface {
+	// Returns a literal which appears in all input the Glob matches, or an empty string if there is no such literal
+	requiredLiteral() string
+}
+
+func (g *globImpl) requiredLiteral() string {
+	return g.literal
+}
+
+// findRequiredLiteral returns the longest run of literal text that must appear in any input matched by the processed
+// tokens, or an empty string if there is none (or if letter case is folded, as the text could appear in another case).
+// Where there are two separators, a separator in the pattern could be either one, so ends the run; so does a separator
+// in a pattern with a globstar adjoining a brace expression, which the globstar may consume in some expansions.
+func findRequiredLiteral(state *parserState) string {
+	if state.options.CaseFolding != CaseSensitive {
+		return ""
+	}
+
+	longest := ""
+	current := new(strings.Builder)
+	endRun := func() {
+		if current.Len() > len(longest) {
+			longest = current.String()
+		}
+		current.Reset()
+	}
+	// Text within brace expressions and extended glob groups is only required in some alternatives
+	depth := 0
+	for _, t := range state.processedTokens {
+		switch t.tokenType {
+		case tcBraceOpen, tcExtGlobOpen:
+			depth++
+			endRun()
+		case tcBraceClose, tcExtGlobClose:
+			depth--
+			endRun()
+		case tcSeparator:
+			if depth == 0 && state.options.AltSeparator == 0 && !state.bracedGlobStar {
+				current.WriteString(t.token)
+			} else {
+				endRun()
+			}
+		case tcLiteral:
+			if depth == 0 {
+				current.WriteString(t.token)
+			}
+		default:
+			endRun()
+		}
+	}
+	endRun()
+	return longest
+}
+
+// literalIndex is an Aho-Corasick automaton, which finds all occurrences of a set of literals in a single pass over the
+// input
+type literalIndex struct {
+	nodes []literalIndexNode
+	// The transitions from the root node, for each byte
+	root [256]int32
+}
+
+type literalIndexNode struct {
+	children map[byte]int32
+	// The node for the longest proper suffix of this node's text which is also a prefix of a literal
+	fail int32
+	// The id of the literal which ends at this node, or -1
+	literal int32
+	// The nearest node on the fail chain at which a literal ends, or -1
+	output int32
+}
+
+// newLiteralIndex builds a literalIndex for the (non-empty) literals, which are identified by their position
+func newLiteralIndex(literals []string) *literalIndex {
+	idx := &literalIndex{
+		nodes: []literalIndexNode{{literal: -1, output: -1}},
+	}
+	for id, literal := range literals {
+		node := int32(0)
+		for i := 0; i < len(literal); i++ {
+			next, ok := idx.nodes[node].children[literal[i]]
+			if !ok {
+				next = int32(len(idx.nodes))
+				idx.nodes = append(idx.nodes, literalIndexNode{literal: -1, output: -1})
+				if idx.nodes[node].children == nil {
+					idx.nodes[node].children = make(map[byte]int32, 1)
+				}
+				idx.nodes[node].children[literal[i]] = next
+			}
+			node = next
+		}
+		idx.nodes[node].literal = int32(id)
+	}
+
+	// Fail links are computed breadth-first, so each node's fail node has always been computed before its own
+	queue := make([]int32, 0, len(idx.nodes))
+	for b, child := range idx.nodes[0].children {
+		idx.root[b] = child
+		queue = append(queue, child)
+	}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		for b, child := range idx.nodes[node].children {
+			idx.nodes[child].fail = idx.next(idx.nodes[node].fail, b)
+			fail := &idx.nodes[idx.nodes[child].fail]
+			if fail.literal >= 0 {
+				idx.nodes[child].output = idx.nodes[child].fail
+			} else {
+				idx.nodes[child].output = fail.output
+			}
+			queue = append(queue, child)
+		}
+	}
+	return idx
+}
+
+// Returns the node reached from the given node by consuming the byte
+func (idx *literalIndex) next(node int32, b byte) int32 {
+	for node != 0 {
+		if child, ok := idx.nodes[node].children[b]; ok {
+			return child
+		}
+		node = idx.nodes[node].fail
+	}
+	return idx.root[b]
+}
+
+// find returns the sorted ids of the literals which appear in the input
+func (idx *literalIndex) find(s string) []int {
+	var found []int
+	node := int32(0)
+	for i := 0; i < len(s); i++ {
+		node = idx.next(node, s[i])
+		for out := node; out > 0; out = idx.nodes[out].output {
+			if literal := idx.nodes[out].literal; literal >= 0 {
+				found = append(found, int(literal))
+			}
+		}
+	}
+
+	// Literals may appear more than once
+	sort.Ints(found)
+	result := found[:0]
+	for i, literal := range found {
+		if i == 0 || literal != found[i-1] {
+			result = append(result, literal)
+		}
+	}
+	return result
+}
@@ -0,0 +1,78 @@
+package ohmyglob
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequiredLiteral(t *testing.T) {
+	extGlobOptions := &Options{
+		Separator:    '/',
+		MatchAtStart: true,
+		MatchAtEnd:   true,
+		ExtGlob:      true,
+	}
+	cases := []struct {
+		pattern string
+		options *Options
+		literal string
+	}{
+		{`foo/bar`, nil, `foo/bar`},
+		{`src/**/*_test.go`, nil, `_test.go`},
+		{`**/vendor/**`, nil, `vendor`},
+		{`!foo/*`, nil, `foo/`},
+		{`a{bcd,efg}hi`, nil, `hi`},
+		{`*.{go,proto}`, nil, `.`},
+		{`{1..10}`, nil, ``},
+		{`f\*\*/x`, nil, `f**/x`},
+		{`[ab]cd?`, nil, `cd`},
+		{`log/!(*.tmp)`, extGlobOptions, `log/`},
+		{`foo/bar`, &Options{Separator: '/', CaseFolding: FoldASCII}, ``},
+	}
+
+	for _, c := range cases {
+		glob, err := Compile(c.pattern, c.options)
+		if assert.NoError(t, err, "Compiling `%s`", c.pattern) {
+			assert.Equal(t, c.literal, glob.(prefilterable).requiredLiteral(), "Unexpected literal for `%s`", c.pattern)
+		}
+	}
+}
+
+func TestLiteralIndex(t *testing.T) {
+	idx := newLiteralIndex([]string{`he`, `she`, `his`, `hers`, `.go`, `_test.go`})
+	assert.Equal(t, []int{0, 1, 3}, idx.find(`ushers`))
+	assert.Equal(t, []int{2}, idx.find(`this`))
+	assert.Equal(t, []int{4, 5}, idx.find(`foo_test.go`))
+	assert.Equal(t, []int{4}, idx.find(`a.go/b.go`))
+	assert.Empty(t, idx.find(``))
+	assert.Empty(t, idx.find(`xyz`))
+}
+
+// Compares the globs matched by a prefiltered GlobSet with those matched by each glob individually
+func TestGlobSet_Prefilter(t *testing.T) {
+	patterns := []string{`**/*.go`, `**/*_test.go`, `!vendor/**`, `vendor/keep/**`, `src/*/main.go`, `*`, `**/docs/*.md`,
+		`{src,pkg}/**/*.proto`, `!**/testdata/**`, `src/**`}
+	set, err := CompileGlobSet(patterns, DefaultOptions)
+	assert.NoError(t, err)
+	globs := set.Globs()
+	assert.NotNil(t, set.(*globSetImpl).literals)
+
+	inputs := append([]string{`vendor/keep/a_test.go`, `src/x/testdata/y.go`, `README`, `docs/a.md`},
+		benchmarkPaths[:200]...)
+	for _, input := range inputs {
+		var expected []Glob
+		for _, glob := range globs {
+			if glob.MatchString(input) {
+				expected = append(expected, glob)
+			}
+		}
+		assert.Equal(t, expected, set.AllMatchingGlobs([]byte(input)), "Unexpected matches for `%s`", input)
+	}
+
+	// Later globs still take precedence, whether or not they were prefiltered
+	assert.True(t, set.MatchString(`vendor/keep/a.go`))
+	assert.False(t, set.MatchString(`vendor/a.go`))
+	assert.False(t, set.MatchString(`pkg/testdata/a.go`))
+	assert.Equal(t, `src/**`, set.MatchingGlob([]byte(`src/a/main.go`)).String())
+}
@@ -0,0 +1,122 @@
+package ohmyglob
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// prefixMatcher answers CouldMatchPrefix queries for a set of Globs, using an automaton of their regular expressions
+// which is built when first needed
+type prefixMatcher struct {
+	once      sync.Once
+	automaton *setAutomaton
+	// The index of the Glob of each of the automaton's programs
+	progGlobs []int
+}
+
+// separated is implemented by Globs which know their separator
+type separated interface {
+	globSeparator() rune
+}
+
+func (g *globImpl) globSeparator() rune {
+	return g.options.Separator
+}
+
+// live reports, for each of the Globs, whether it could match some string beginning with dir followed by its
+// separator. Globs whose regular expressions aren't available are assumed to be able to.
+func (p *prefixMatcher) live(globs []Glob, dir string) []bool {
+	p.once.Do(func() {
+		res := make([]*regexp.Regexp, 0, len(globs))
+		for i, glob := range globs {
+			if a, ok := glob.(automatable); ok {
+				re, _ := a.automatonRegexp()
+				res = append(res, re)
+				p.progGlobs = append(p.progGlobs, i)
+			}
+		}
+		// The regular expressions have all been compiled before, so can't fail to compile now
+		p.automaton, _ = newSetAutomaton(res)
+	})
+
+	live := make([]bool, len(globs))
+	separators := make(map[rune]bool, 1)
+	for i, glob := range globs {
+		_, isAutomatable := glob.(automatable)
+		g, isSeparated := glob.(separated)
+		if !isAutomatable || !isSeparated || p.automaton == nil {
+			live[i] = true
+		} else {
+			separators[g.globSeparator()] = true
+		}
+	}
+
+	// Globs with different separators are queried separately
+	for separator := range separators {
+		for _, prog := range p.automaton.prefixMatches(dir + string(separator)) {
+			globIdx := p.progGlobs[prog]
+			if globs[globIdx].(separated).globSeparator() == separator {
+				live[globIdx] = true
+			}
+		}
+	}
+	return live
+}
+
+func (g *globImpl) CouldMatchPrefix(dir string) bool {
+	return g.prefix.live([]Glob{g}, dir)[0]
+}
+
+// prefixCoverer is implemented by Globs which can tell whether they match every path beneath a directory
+type prefixCoverer interface {
+	// Reports whether the Glob matches every string beginning with dir followed by the separator (other than those
+	// consisting only of them, or containing a newline, which globstars don't match); it may report false even if it
+	// does
+	coversPrefix(dir string) bool
+}
+
+func (g *prefixGlob) coversPrefix(dir string) bool {
+	return dir == g.prefix || strings.HasPrefix(dir, g.prefix+g.separator)
+}
+
+func (g *nativeGlob) coversPrefix(dir string) bool {
+	if g.matchAll {
+		return true
+	}
+
+	// Patterns such as "src/*/**" cover a directory if its leading segments match those before the trailing globstar
+	last := len(g.segments) - 1
+	if last < 0 || !g.segments[last].globStar {
+		return false
+	}
+	segments := strings.Split(dir, string(g.separator))
+	if len(segments) < last {
+		return false
+	}
+	for i, segment := range g.segments[:last] {
+		if segment.globStar || !matchSegment(segment.elements, segments[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (g *globSetImpl) CouldMatchPrefix(dir string) bool {
+	live := g.prefix.live(g.globs, dir)
+	for i := len(g.globs) - 1; i >= 0; i-- {
+		glob := g.globs[i]
+		if !glob.IsNegative() {
+			if live[i] {
+				return true
+			}
+			continue
+		}
+		// A negative Glob which matches everything beneath the directory excludes it, unless a later Glob could match
+		// something beneath it (which, with MostSpecific precedence, could be any Glob)
+		if c, ok := glob.(prefixCoverer); ok && g.precedence == LastMatch && c.coversPrefix(dir) {
+			return false
+		}
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package ohmyglob
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCouldMatchPrefix(t *testing.T) {
+	cases := []struct {
+		pattern string
+		options *Options
+		could   []string
+		couldNt []string
+	}{
+		{`src/**/*.go`, nil, []string{`src`, `src/a`, `src/a/b`}, []string{`docs`, `srcs`, `sr`}},
+		{`src/*/main.go`, nil, []string{`src`, `src/a`}, []string{`src/a/b`, `docs`}},
+		{`**/vendor/**`, nil, []string{`a`, `a/vendor`, `vendor/x/y`}, nil},
+		{`{src,lib}/*.c`, nil, []string{`src`, `lib`}, []string{`src/x`, `docs`}},
+		{`!docs/*`, nil, []string{`docs`}, []string{`src`}},
+		{`*.md`, nil, nil, []string{`docs`, `a/b`}},
+		{`docs/*.md`, &Options{Separator: '/', MatchAtEnd: true}, []string{`x`, `x/docs`}, nil},
+		{`DOCS/*`, &Options{Separator: '/', MatchAtStart: true, MatchAtEnd: true, CaseFolding: FoldASCII},
+			[]string{`docs`, `Docs`}, []string{`doc`}},
+		{`a:**:b`, &Options{Separator: ':', MatchAtStart: true, MatchAtEnd: true}, []string{`a`, `a:x`},
+			[]string{`b`}},
+		{`log/!(*.tmp)`, &Options{Separator: '/', MatchAtStart: true, MatchAtEnd: true, ExtGlob: true},
+			[]string{`log`}, []string{`tmp`, `log/x`}},
+	}
+
+	for _, c := range cases {
+		glob, err := Compile(c.pattern, c.options)
+		if !assert.NoError(t, err, "Compiling `%s`", c.pattern) {
+			continue
+		}
+		for _, dir := range c.could {
+			assert.True(t, glob.CouldMatchPrefix(dir), "`%s` could match beneath `%s`", c.pattern, dir)
+		}
+		for _, dir := range c.couldNt {
+			assert.False(t, glob.CouldMatchPrefix(dir), "`%s` can't match beneath `%s`", c.pattern, dir)
+		}
+	}
+}
+
+func TestGlobSet_CouldMatchPrefix(t *testing.T) {
+	set, err := CompileGlobSet([]string{`**/*.go`, `!vendor/**`, `vendor/keep/**`, `!**/testdata/**`, `docs/*.md`},
+		DefaultOptions)
+	assert.NoError(t, err)
+
+	assert.True(t, set.CouldMatchPrefix(`src`))
+	assert.True(t, set.CouldMatchPrefix(`vendor`))
+	assert.True(t, set.CouldMatchPrefix(`vendor/keep`))
+	assert.False(t, set.CouldMatchPrefix(`vendor/lib`))
+	// Negative globs which aren't simple prefixes can't prune
+	assert.True(t, set.CouldMatchPrefix(`src/testdata`))
+
+	// With MostSpecific precedence, any glob may take precedence over a negative one
+	assert.True(t, set.WithPrecedence(MostSpecific).CouldMatchPrefix(`vendor/lib`))
+
+	set, err = CompileGlobSet([]string{`src/**`, `!src/*/gen/**`, `docs/*.md`}, DefaultOptions)
+	assert.NoError(t, err)
+	assert.True(t, set.CouldMatchPrefix(`src/a`))
+	assert.False(t, set.CouldMatchPrefix(`src/a/gen`))
+	assert.False(t, set.CouldMatchPrefix(`src/a/gen/b`))
+	assert.True(t, set.CouldMatchPrefix(`docs`))
+	assert.False(t, set.CouldMatchPrefix(`docs/a`))
+	assert.False(t, set.CouldMatchPrefix(`other`))
+}
+
+func TestWalkFS_Pruning(t *testing.T) {
+	set, err := CompileGlobSet([]string{`**/*.go`, `!src/vendor/**`}, DefaultOptions)
+	assert.NoError(t, err)
+	recorder := &openRecorder{FS: testFS}
+	paths, err := GlobFS(recorder, set)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{`src/main.go`, `src/main_test.go`, `src/util/strings.go`, `src/util/strings_test.go`}, paths)
+	assert.NotContains(t, recorder.opened, `src/vendor`)
+
+	glob, err := Compile(`services/*/config.yaml`, nil)
+	assert.NoError(t, err)
+	recorder = &openRecorder{FS: testFS}
+	_, err = GlobFS(recorder, glob)
+	assert.NoError(t, err)
+	assert.NotContains(t, recorder.opened, `services/billing/extra`)
+}
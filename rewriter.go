@@ -0,0 +1,250 @@
+package ohmyglob
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Rewriter maps the strings matched by a Glob to new strings, built from a template which refers to the Glob's
+// captures. For example, the pattern "old/{a:*}/{rest:**}" and the template "new/{a}/v2/{rest}" rewrite
+// "old/x/y/z.go" to "new/x/v2/y/z.go". A Rewriter is immutable.
+type Rewriter interface {
+	// Rewrite returns the rewritten string, and true; or, if the string is not matched (or the Glob is negative), an
+	// empty string and false
+	Rewrite(s string) (string, bool)
+	// Glob returns the Glob that strings must match to be rewritten
+	Glob() Glob
+	// Template returns the template that rewritten strings are built from
+	Template() string
+	// String returns the pattern and the template, separated by an arrow
+	String() string
+}
+
+// RewriterSet represents an ordered set of Rewriters. A string is rewritten by the Rewriter whose Glob takes precedence
+// in matching it (as in a GlobSet, later Rewriters take precedence); if that Glob is negative, the string is not
+// rewritten. A RewriterSet is immutable.
+type RewriterSet interface {
+	// Rewrite returns the string rewritten by the Rewriter that takes precedence, and true; or, if there is no such
+	// Rewriter (or its Glob is negative), an empty string and false
+	Rewrite(s string) (string, bool)
+	// MatchingRewriter returns the Rewriter that takes precedence in matching the string, or nil if there is none
+	MatchingRewriter(s string) Rewriter
+	// Rewriters returns the ordered Rewriters contained within the set
+	Rewriters() []Rewriter
+	// String returns the Rewriters' patterns and templates
+	String() string
+}
+
+// RewriteRule is a pattern, and the template for rewriting the strings it matches
+type RewriteRule struct {
+	Pattern  string
+	Template string
+}
+
+// templatePart is a component of a rewrite template: either literal text, or a reference to a submatch
+type templatePart struct {
+	literal  string
+	submatch int
+}
+
+type rewriterImpl struct {
+	glob     Glob
+	template string
+	parts    []templatePart
+}
+
+// NewRewriter constructs a Rewriter from a Glob and a template. In the template, "{name}" is replaced by the text of
+// the Glob's named capture, and "{n}" by the text of its nth wildcard (counting from 1; "{0}" is the whole match).
+// The Escaper the Glob was compiled with escapes the next character of the template (so "\{" is a literal brace). Any
+// error is a *PatternError describing the problem with the template.
+func NewRewriter(glob Glob, template string) (Rewriter, error) {
+	names := []string(nil)
+	if named, ok := glob.(interface{ SubexpNames() []string }); ok {
+		names = named.SubexpNames()
+	}
+	escaper := Escaper
+	if e, ok := glob.(escaped); ok {
+		escaper = e.globEscaper()
+	}
+	parts, err := parseTemplate(template, names, escaper)
+	if err != nil {
+		return nil, completePatternError(err, template, 0)
+	}
+	return &rewriterImpl{
+		glob:     glob,
+		template: template,
+		parts:    parts,
+	}, nil
+}
+
+// CompileRewriter constructs a Rewriter from a pattern, which is compiled to a Glob with the given options, and a
+// template (see NewRewriter). Any error is a *PatternError.
+func CompileRewriter(pattern, template string, options *Options) (Rewriter, error) {
+	glob, err := Compile(pattern, options)
+	if err != nil {
+		return nil, err
+	}
+	return NewRewriter(glob, template)
+}
+
+// escaped is implemented by Globs which know their escaper
+type escaped interface {
+	globEscaper() rune
+}
+
+func (g *globImpl) globEscaper() rune {
+	return g.options.escaper()
+}
+
+// parseTemplate splits a template into parts, resolving references to the submatches with the given names
+func parseTemplate(template string, names []string, escaper rune) ([]templatePart, error) {
+	parts := make([]templatePart, 0, 4)
+	literal := new(strings.Builder)
+	flushLiteral := func() {
+		if literal.Len() > 0 {
+			parts = append(parts, templatePart{literal: literal.String(), submatch: -1})
+			literal.Reset()
+		}
+	}
+
+	for i := 0; i < len(template); {
+		r, width := utf8.DecodeRuneInString(template[i:])
+		switch r {
+		case escaper:
+			i += width
+			if i < len(template) {
+				r, width = utf8.DecodeRuneInString(template[i:])
+				literal.WriteRune(r)
+			}
+		case '{':
+			end := strings.IndexByte(template[i:], '}')
+			if end < 0 {
+				return nil, newPatternError(ErrUnterminatedBrace, i, template[i:], "unterminated reference")
+			}
+			reference := template[i+1 : i+end]
+			submatch := -1
+			if n, err := strconv.Atoi(reference); err == nil && n >= 0 && n < len(names) {
+				submatch = n
+			} else if reference != "" {
+				for j, name := range names {
+					if name == reference {
+						submatch = j
+					}
+				}
+			}
+			if submatch < 0 {
+				return nil, newPatternError(ErrUnknownCapture, i, template[i:i+end+1],
+					"reference to unknown capture \"%s\"", reference)
+			}
+			flushLiteral()
+			parts = append(parts, templatePart{submatch: submatch})
+			width = end + 1
+		default:
+			literal.WriteRune(r)
+		}
+		i += width
+	}
+	flushLiteral()
+	return parts, nil
+}
+
+// rewrite builds the rewritten string from the submatch indices of a match
+func (r *rewriterImpl) rewrite(s string, loc []int) string {
+	buf := new(strings.Builder)
+	for _, part := range r.parts {
+		if part.submatch < 0 {
+			buf.WriteString(part.literal)
+		} else if start := loc[2*part.submatch]; start >= 0 {
+			buf.WriteString(s[start:loc[2*part.submatch+1]])
+		}
+	}
+	return buf.String()
+}
+
+func (r *rewriterImpl) Rewrite(s string) (string, bool) {
+	if r.glob.IsNegative() {
+		return "", false
+	}
+	loc := r.glob.FindStringSubmatchIndex(s)
+	if loc == nil {
+		return "", false
+	}
+	return r.rewrite(s, loc), true
+}
+
+func (r *rewriterImpl) Glob() Glob {
+	return r.glob
+}
+
+func (r *rewriterImpl) Template() string {
+	return r.template
+}
+
+func (r *rewriterImpl) String() string {
+	return r.glob.String() + " -> " + r.template
+}
+
+type rewriterSetImpl struct {
+	rewriters []Rewriter
+	globs     *globSetImpl
+}
+
+// NewRewriterSet constructs a RewriterSet from a slice of Rewriters
+func NewRewriterSet(rewriters []Rewriter) (RewriterSet, error) {
+	globs := make([]Glob, len(rewriters))
+	for i, rewriter := range rewriters {
+		globs[i] = rewriter.Glob()
+	}
+	globSet, err := newGlobSet(globs)
+	if err != nil {
+		return nil, err
+	}
+	return &rewriterSetImpl{
+		rewriters: append([]Rewriter(nil), rewriters...),
+		globs:     globSet,
+	}, nil
+}
+
+// CompileRewriterSet constructs a RewriterSet from a slice of rules, whose patterns will be compiled with the given
+// options. Any error is a *PatternError, whose Index is that of the rule which could not be compiled.
+func CompileRewriterSet(rules []RewriteRule, options *Options) (RewriterSet, error) {
+	rewriters := make([]Rewriter, len(rules))
+	for i, rule := range rules {
+		rewriter, err := CompileRewriter(rule.Pattern, rule.Template, options)
+		if err != nil {
+			if patternErr, ok := err.(*PatternError); ok {
+				patternErr.Index = i
+			}
+			return nil, err
+		}
+		rewriters[i] = rewriter
+	}
+	return NewRewriterSet(rewriters)
+}
+
+func (s *rewriterSetImpl) MatchingRewriter(str string) Rewriter {
+	if i := s.globs.winner(s.globs.matchingIndices(str)); i >= 0 {
+		return s.rewriters[i]
+	}
+	return nil
+}
+
+func (s *rewriterSetImpl) Rewrite(str string) (string, bool) {
+	if rewriter := s.MatchingRewriter(str); rewriter != nil {
+		return rewriter.Rewrite(str)
+	}
+	return "", false
+}
+
+func (s *rewriterSetImpl) Rewriters() []Rewriter {
+	return append([]Rewriter(nil), s.rewriters...)
+}
+
+func (s *rewriterSetImpl) String() string {
+	strs := make([]string, len(s.rewriters))
+	for i, rewriter := range s.rewriters {
+		strs[i] = rewriter.String()
+	}
+	return strings.Join(strs, ", ")
+}
@@ -0,0 +1,99 @@
+package ohmyglob
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRewriter(t *testing.T) {
+	rewriter, err := CompileRewriter(`old/{a:*}/{rest:**}`, `new/{a}/v2/{rest}`, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, `old/{a:*}/{rest:**} -> new/{a}/v2/{rest}`, rewriter.String())
+
+	rewritten, ok := rewriter.Rewrite(`old/x/y/z.go`)
+	assert.True(t, ok)
+	assert.Equal(t, `new/x/v2/y/z.go`, rewritten)
+	rewritten, ok = rewriter.Rewrite(`other/x/y`)
+	assert.False(t, ok)
+	assert.Equal(t, ``, rewritten)
+
+	// Positional references, escaped braces, and wildcards which matched nothing
+	rewriter, err = CompileRewriter(`src/**/*.{c,h}`, `\{{1}\}/{2}.txt ({0})`, nil)
+	assert.NoError(t, err)
+	rewritten, ok = rewriter.Rewrite(`src/a/b/main.c`)
+	assert.True(t, ok)
+	assert.Equal(t, `{a/b}/main.txt (src/a/b/main.c)`, rewritten)
+	rewritten, ok = rewriter.Rewrite(`src/main.h`)
+	assert.True(t, ok)
+	assert.Equal(t, `{}/main.txt (src/main.h)`, rewritten)
+
+	// Negative globs never rewrite
+	rewriter, err = CompileRewriter(`!old/{a:*}`, `new/{a}`, nil)
+	assert.NoError(t, err)
+	_, ok = rewriter.Rewrite(`old/x`)
+	assert.False(t, ok)
+
+	// Templates are escaped with the Escaper of the Glob's options
+	rewriter, err = CompileRewriter(`C:\Users\{user:*}\**`, "D:\\Backup\\`{{user}`}", WindowsOptions)
+	assert.NoError(t, err)
+	rewritten, ok = rewriter.Rewrite(`c:/users/ann/docs/a.txt`)
+	assert.True(t, ok)
+	assert.Equal(t, `D:\Backup\{ann}`, rewritten)
+}
+
+func TestRewriter_InvalidTemplate(t *testing.T) {
+	cases := []struct {
+		template string
+		kind     PatternErrorKind
+		offset   int
+	}{
+		{`new/{b}`, ErrUnknownCapture, 4},
+		{`new/{3}`, ErrUnknownCapture, 4},
+		{`new/{}`, ErrUnknownCapture, 4},
+		{`new/{a`, ErrUnterminatedBrace, 4},
+	}
+	for _, c := range cases {
+		_, err := CompileRewriter(`old/{a:*}/*`, c.template, nil)
+		var patternErr *PatternError
+		if assert.True(t, errors.As(err, &patternErr), "Template `%s` should be rejected", c.template) {
+			assert.Equal(t, c.kind, patternErr.Kind, "Unexpected kind for `%s`", c.template)
+			assert.Equal(t, c.offset, patternErr.Offset, "Unexpected offset for `%s`", c.template)
+			assert.Equal(t, c.template, patternErr.Pattern)
+		}
+	}
+}
+
+func TestRewriterSet(t *testing.T) {
+	set, err := CompileRewriterSet([]RewriteRule{
+		{`old/{a:*}/{rest:**}`, `new/{a}/{rest}`},
+		{`old/legacy/{rest:**}`, `archive/{rest}`},
+		{`!old/legacy/keep/**`, ``},
+		{`**/*.tmp`, `tmp/{2}.tmp`},
+	}, DefaultOptions)
+	assert.NoError(t, err)
+	assert.Len(t, set.Rewriters(), 4)
+
+	cases := map[string]string{
+		`old/x/y`:               `new/x/y`,
+		`old/legacy/y`:          `archive/y`,
+		`old/legacy/keep/y`:     ``,
+		`old/legacy/keep/y.tmp`: `tmp/y.tmp`,
+		`other/y`:               ``,
+	}
+	for input, expected := range cases {
+		rewritten, ok := set.Rewrite(input)
+		assert.Equal(t, expected != ``, ok, "Unexpected result for `%s`", input)
+		assert.Equal(t, expected, rewritten, "Unexpected rewrite of `%s`", input)
+	}
+	assert.Equal(t, `!old/legacy/keep/**`, set.MatchingRewriter(`old/legacy/keep/y`).Glob().String())
+	assert.Nil(t, set.MatchingRewriter(`other/y`))
+
+	_, err = CompileRewriterSet([]RewriteRule{{`a/*`, `b`}, {`c/{x:*}`, `{y}`}}, nil)
+	var patternErr *PatternError
+	if assert.True(t, errors.As(err, &patternErr)) {
+		assert.Equal(t, 1, patternErr.Index)
+		assert.Equal(t, ErrUnknownCapture, patternErr.Kind)
+	}
+}
@@ -0,0 +1,92 @@
+package ohmyglob
+
+import "unicode/utf8"
+
+// Precedence determines which Glob takes precedence when more than one Glob in a set matches the same string
+type Precedence uint8
+
+const (
+	// LastMatch gives precedence to the last matching Glob in the set
+	LastMatch = Precedence(0x0)
+	// MostSpecific gives precedence to the matching Glob with the highest Specificity, or, of those with equal
+	// Specificity, the last in the set
+	MostSpecific = Precedence(0x1)
+)
+
+// Field widths of a specificity score (see Glob.Specificity); counts are capped so they never overflow into the next
+// field
+const (
+	specificityGlobStarBits = 16
+	specificityDepthBits    = 16
+)
+
+// computeSpecificity calculates the specificity score of a pattern from its processed tokens. The score is made up of
+// three fields, compared in order:
+//
+//  1. The number of literal characters (including separators) outside brace expressions and extended glob groups;
+//     more is more specific
+//  2. The number of globstars; fewer is more specific
+//  3. The depth to which the pattern is anchored: the number of separators in its literal prefix (before any wildcard
+//     or group), if it must match at the start of the input, or 0 otherwise; deeper is more specific
+func computeSpecificity(state *parserState) int64 {
+	literals, globStars, depth := 0, 0, 0
+	groupDepth := 0
+	inPrefix := state.options.MatchAtStart
+	for _, t := range state.processedTokens {
+		switch t.tokenType {
+		case tcLiteral:
+			if groupDepth == 0 {
+				literals += utf8.RuneCountInString(t.token)
+			}
+		case tcSeparator:
+			if groupDepth == 0 {
+				literals++
+			}
+			if inPrefix {
+				depth++
+			}
+		case tcGlobStar:
+			globStars++
+			inPrefix = false
+		case tcStar, tcAny, tcCharClass:
+			inPrefix = false
+		case tcBraceOpen, tcExtGlobOpen:
+			groupDepth++
+			inPrefix = false
+		case tcBraceClose, tcExtGlobClose:
+			groupDepth--
+		}
+	}
+
+	capped := func(n, bits int) int64 {
+		if max := 1<<bits - 1; n > max {
+			return int64(max)
+		}
+		return int64(n)
+	}
+	score := int64(literals)
+	score = score<<specificityGlobStarBits | (1<<specificityGlobStarBits - 1 - capped(globStars, specificityGlobStarBits))
+	score = score<<specificityDepthBits | capped(depth, specificityDepthBits)
+	return score
+}
+
+func (g *globImpl) Specificity() int64 {
+	return g.specificity
+}
+
+// winner returns the index of the Glob which takes precedence, given the ascending indices of the matching Globs (or -1
+// if there are none)
+func (g *globSetImpl) winner(matches []int) int {
+	if len(matches) == 0 {
+		return -1
+	}
+	winner := matches[len(matches)-1]
+	if g.precedence == MostSpecific {
+		for _, i := range matches {
+			if g.specificity[i] >= g.specificity[winner] {
+				winner = i
+			}
+		}
+	}
+	return winner
+}
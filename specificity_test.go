@@ -0,0 +1,78 @@
+package ohmyglob
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpecificity(t *testing.T) {
+	// Each pattern is less specific than the one before
+	ordered := []string{
+		`services/auth/main.go`,
+		`services/auth/*.go`,
+		`services/*/main.go`,
+		`services/auth/**`,
+		`**/auth/main.go`,
+		`services/**`,
+		`*/*`,
+		`**/*`,
+		`**/*/**`,
+	}
+	for i := 1; i < len(ordered); i++ {
+		more, err := Compile(ordered[i-1], nil)
+		assert.NoError(t, err)
+		less, err := Compile(ordered[i], nil)
+		assert.NoError(t, err)
+		assert.True(t, more.Specificity() > less.Specificity(), "`%s` should be more specific than `%s`",
+			ordered[i-1], ordered[i])
+	}
+
+	// Negation, literals within groups and wildcards other than globstars don't count
+	for _, pair := range [][2]string{{`a/*.go`, `!a/*.go`}, {`a/*`, `a/{bcd,efg}`}, {`**`, `**/*`}} {
+		glob1, _ := Compile(pair[0], nil)
+		glob2, _ := Compile(pair[1], nil)
+		assert.Equal(t, glob1.Specificity(), glob2.Specificity(), "`%s` and `%s` should be equally specific",
+			pair[0], pair[1])
+	}
+
+	// Anchoring depth only counts for patterns anchored at the start
+	anchored, _ := Compile(`a/b*`, nil)
+	unanchored, _ := Compile(`a/b*`, &Options{Separator: '/', MatchAtEnd: true})
+	assert.True(t, anchored.Specificity() > unanchored.Specificity())
+}
+
+func TestGlobSet_MostSpecific(t *testing.T) {
+	set, err := CompileGlobSet([]string{`services/auth/**`, `!**/*.tmp`, `**`, `services/**`}, DefaultOptions)
+	assert.NoError(t, err)
+	assert.Equal(t, `services/**`, set.MatchingGlob([]byte(`services/auth/x.go`)).String())
+
+	specific := set.WithPrecedence(MostSpecific)
+	assert.Equal(t, `services/auth/**`, specific.MatchingGlob([]byte(`services/auth/x.go`)).String())
+	assert.Equal(t, `services/**`, specific.MatchingGlob([]byte(`services/billing/x.go`)).String())
+	assert.Equal(t, `!**/*.tmp`, specific.MatchingGlob([]byte(`x.tmp`)).String())
+	assert.False(t, specific.MatchString(`x.tmp`))
+	assert.True(t, specific.MatchString(`services/auth/x.tmp`))
+	// The original set is unchanged
+	assert.Equal(t, `services/**`, set.MatchingGlob([]byte(`services/auth/x.go`)).String())
+
+	// Ties are broken by order, with later globs taking precedence
+	set, err = CompileGlobSet([]string{`a/*.go`, `a/?.go`}, DefaultOptions)
+	assert.NoError(t, err)
+	assert.Equal(t, `a/?.go`, set.WithPrecedence(MostSpecific).MatchingGlob([]byte(`a/b.go`)).String())
+}
+
+func TestGlobMap_MostSpecific(t *testing.T) {
+	routes, err := CompileGlobMap([]GlobMapEntry[string]{
+		{`api/{version:*}/users/{id:*}`, `user`},
+		{`api/**`, `api`},
+	}, DefaultOptions)
+	assert.NoError(t, err)
+	value, _ := routes.Get(`api/v1/users/42`)
+	assert.Equal(t, `api`, value)
+
+	match, ok := routes.WithPrecedence(MostSpecific).Lookup(`api/v1/users/42`)
+	assert.True(t, ok)
+	assert.Equal(t, `user`, match.Value)
+	assert.Equal(t, map[string]string{"version": "v1", "id": "42"}, match.Captures)
+}
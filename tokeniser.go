@@ -3,6 +3,8 @@ package ohmyglob
 import (
 	"bytes"
 	"io"
+	"strings"
+	"unicode/utf8"
 )
 
 type tc uint8
@@ -22,6 +24,23 @@ const (
 	tcAny = tc(0x5)
 	// A separator
 	tcSeparator = tc(0x6)
+	// A bracket expression, matching a single character from (or not from) a set
+	tcCharClass = tc(0x7)
+	// The opening of a brace expression
+	tcBraceOpen = tc(0x8)
+	// The separator between alternatives in a brace expression
+	tcBraceSeparator = tc(0x9)
+	// The closing of a brace expression
+	tcBraceClose = tc(0xa)
+	// The opening of an extended glob group, eg. "+(" or "!("
+	tcExtGlobOpen = tc(0xb)
+	// The separator between alternatives in an extended glob group
+	tcExtGlobSeparator = tc(0xc)
+	// The closing of an extended glob group
+	tcExtGlobClose = tc(0xd)
+	// A named capture, eg. "{svc:*}"; only used while tokenising, as a named capture is yielded as a star or globstar
+	// (whose token is the whole capture)
+	tcCapture = tc(0xe)
 )
 
 // Tokenises a glob input; implements an API very similar to that of bufio.Scanner (though is not identical)
@@ -35,6 +54,24 @@ type globTokeniser struct {
 	peekToken     string
 	peekTokenType tc
 	peekErr       error
+	// Runes that have been read from the input, but pushed back to be read again (in reverse order)
+	pushback []rune
+	// The byte offset of the next rune to be read, and of the current and peeked tokens
+	offset          int
+	tokenOffset     int
+	peekTokenOffset int
+	// The brace expressions and extended glob groups that are currently open
+	braces   []openGroup
+	extGlobs []openGroup
+}
+
+// openGroup is the opening token of a brace expression or extended glob group, and its byte offset within the input
+type openGroup struct {
+	token  string
+	offset int
+	// Set to true for a brace which has a closing brace but doesn't open a brace expression (eg. "{a}"), so that it and
+	// its closing brace are literal
+	literal bool
 }
 
 func newGlobTokeniser(input io.RuneScanner, globOptions *Options) *globTokeniser {
@@ -44,6 +81,169 @@ func newGlobTokeniser(input io.RuneScanner, globOptions *Options) *globTokeniser
 	}
 }
 
+// Reads the next rune, either from the pushback buffer or the input
+func (g *globTokeniser) readRune() (rune, error) {
+	if n := len(g.pushback); n > 0 {
+		r := g.pushback[n-1]
+		g.pushback = g.pushback[:n-1]
+		g.offset += utf8.RuneLen(r)
+		return r, nil
+	}
+	r, size, err := g.input.ReadRune()
+	g.offset += size
+	return r, err
+}
+
+// Pushes a rune back so it will be returned by the next call to readRune; any number of runes may be pushed back
+func (g *globTokeniser) unreadRune(r rune) {
+	g.pushback = append(g.pushback, r)
+	g.offset -= utf8.RuneLen(r)
+}
+
+// Returns whether the next rune to be read is the opening of an extended glob group, without consuming it
+func (g *globTokeniser) nextIsExtGlobOpen() bool {
+	if !g.globOptions.ExtGlob {
+		return false
+	}
+	r, err := g.readRune()
+	if err != nil {
+		return false
+	}
+	g.unreadRune(r)
+	return r == '('
+}
+
+// Returns whether the runes following an opening brace form the rest of a named capture (a name, a colon, one or two
+// stars and a closing brace, eg. "svc:*}"), and if so, the name. No runes are consumed.
+func (g *globTokeniser) peekCaptureName() (string, bool) {
+	read := make([]rune, 0, 16)
+	defer func() {
+		for i := len(read) - 1; i >= 0; i-- {
+			g.unreadRune(read[i])
+		}
+	}()
+
+	for {
+		r, err := g.readRune()
+		if err != nil {
+			return "", false
+		}
+		read = append(read, r)
+		if r == ':' {
+			break
+		}
+		switch r {
+		case ',', '{', '}', '*', '?', '[', g.globOptions.escaper(), g.globOptions.Separator, g.globOptions.altSeparator():
+			return "", false
+		}
+	}
+	name := string(read[:len(read)-1])
+
+	stars := 0
+	for {
+		r, err := g.readRune()
+		if err != nil {
+			return "", false
+		}
+		read = append(read, r)
+		if r == '*' && stars < 2 {
+			stars++
+			continue
+		}
+		return name, r == '}' && stars > 0
+	}
+}
+
+// Returns the runes following an opening bracket up to and including the closing bracket of the bracket expression,
+// and whether the expression is closed at all (as in bash, an opening bracket which isn't closed is literal). No runes
+// are consumed.
+func (g *globTokeniser) peekCharClass() (string, bool) {
+	buf := bytes.NewBufferString("[")
+	err := g.parseCharClass(buf, 0)
+	read := []rune(buf.String())[1:]
+	for i := len(read) - 1; i >= 0; i-- {
+		g.unreadRune(read[i])
+	}
+	return string(read), err == nil
+}
+
+// Returns whether the runes following an opening brace complete a brace expression, and whether the brace is closed
+// at all. As in bash, a brace expression is closed, and contains either a comma at its top level or only a sequence
+// expression (eg. "1..10"); other braces are literal. No runes are consumed.
+func (g *globTokeniser) peekBraceExpression() (isExpression bool, isClosed bool) {
+	read := make([]rune, 0, 16)
+	defer func() {
+		for i := len(read) - 1; i >= 0; i-- {
+			g.unreadRune(read[i])
+		}
+	}()
+
+	depth := 0
+	hasComma, isNested, escaped := false, false, false
+	for {
+		r, err := g.readRune()
+		if err != nil {
+			return false, false
+		}
+		read = append(read, r)
+
+		switch {
+		case escaped:
+			escaped = false
+		case r == g.globOptions.escaper():
+			escaped = true
+		case r == g.globOptions.Separator || r == g.globOptions.altSeparator():
+		case r == '[':
+			// Bracket expressions are skipped whole, as they are tokenised (so may contain braces and commas)
+			if class, isClosed := g.peekCharClass(); isClosed {
+				for range class {
+					r, _ := g.readRune()
+					read = append(read, r)
+				}
+			}
+		case r == '{':
+			depth++
+			isNested = true
+		case r == '}' && depth > 0:
+			depth--
+		case r == '}':
+			if hasComma {
+				return true, true
+			}
+			_, isSequence, _ := expandSequenceExpression(string(read[:len(read)-1]), 0)
+			return isSequence && !isNested, true
+		case r == ',' && depth == 0:
+			hasComma = true
+		}
+	}
+}
+
+// captureName returns the name of a named capture, given the token of its wildcard (eg. "svc" for "{svc:*}"), or an
+// empty string if the wildcard is not a named capture
+func captureName(token string) string {
+	if !strings.HasPrefix(token, "{") || !strings.HasSuffix(token, "*}") {
+		return ""
+	}
+	return token[1:strings.IndexByte(token, ':')]
+}
+
+// isValidCaptureName returns whether the name of a named capture is valid: a letter or underscore, followed by any
+// number of letters, digits and underscores (as in a Go identifier, but only ASCII)
+func isValidCaptureName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		switch {
+		case r == '_', r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+		case r >= '0' && r <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
 // Advances by a single token
 func (g *globTokeniser) parse(lastTokenType tc) (string, tc, error) {
 	var err error
@@ -54,26 +254,89 @@ func (g *globTokeniser) parse(lastTokenType tc) (string, tc, error) {
 
 	for {
 		var r rune
-		r, _, err = g.input.ReadRune()
+		r, err = g.readRune()
 		if err != nil {
 			break
 		}
 
 		runeType := tcUnknown
+		// Literal braces are tracked (or rejected, if unterminated when parsing strictly) once the rune is consumed
+		openLiteralBrace, closeLiteralBrace, unterminatedBrace := false, false, false
 		switch r {
-		case Escaper:
+		case g.globOptions.escaper():
 			runeType = tcEscaper
+		case g.globOptions.Separator, g.globOptions.altSeparator():
+			// Separators take precedence over runes which only have meaning in some contexts (eg. a pipe, outside an
+			// extended glob group)
+			runeType = tcSeparator
 		case '*':
-			if tokenType == tcStar {
+			if !escaped && g.nextIsExtGlobOpen() {
+				runeType = tcExtGlobOpen
+			} else if tokenType == tcStar {
 				runeType = tcGlobStar
 				tokenType = tcGlobStar
 			} else {
 				runeType = tcStar
 			}
 		case '?':
-			runeType = tcAny
-		case g.globOptions.Separator:
-			runeType = tcSeparator
+			if !escaped && g.nextIsExtGlobOpen() {
+				runeType = tcExtGlobOpen
+			} else {
+				runeType = tcAny
+			}
+		case '+', '@', '!':
+			if !escaped && g.nextIsExtGlobOpen() {
+				runeType = tcExtGlobOpen
+			} else {
+				runeType = tcLiteral
+			}
+		case '|':
+			// Pipes and closing parentheses only have meaning within an extended glob group
+			if len(g.extGlobs) > 0 {
+				runeType = tcExtGlobSeparator
+			} else {
+				runeType = tcLiteral
+			}
+		case ')':
+			if len(g.extGlobs) > 0 {
+				runeType = tcExtGlobClose
+			} else {
+				runeType = tcLiteral
+			}
+		case '[':
+			// An opening bracket which isn't closed is literal, unless parsing strictly (in which case it is rejected
+			// as an unterminated bracket expression)
+			if _, isClosed := g.peekCharClass(); isClosed || g.globOptions.Strict {
+				runeType = tcCharClass
+			} else {
+				runeType = tcLiteral
+			}
+		case '{':
+			if escaped {
+				runeType = tcLiteral
+			} else if _, isCapture := g.peekCaptureName(); isCapture {
+				runeType = tcCapture
+			} else if isExpression, isClosed := g.peekBraceExpression(); isExpression {
+				runeType = tcBraceOpen
+			} else {
+				runeType = tcLiteral
+				openLiteralBrace = isClosed
+				unterminatedBrace = !isClosed && g.globOptions.Strict
+			}
+		case ',':
+			// Commas and closing braces only have meaning within a brace expression
+			if n := len(g.braces); n > 0 && !g.braces[n-1].literal {
+				runeType = tcBraceSeparator
+			} else {
+				runeType = tcLiteral
+			}
+		case '}':
+			if n := len(g.braces); n > 0 && !g.braces[n-1].literal {
+				runeType = tcBraceClose
+			} else {
+				runeType = tcLiteral
+				closeLiteralBrace = n > 0 && !escaped
+			}
 		default:
 			runeType = tcLiteral
 		}
@@ -86,17 +349,64 @@ func (g *globTokeniser) parse(lastTokenType tc) (string, tc, error) {
 
 		if (tokenType != tcUnknown) && (tokenType != runeType) {
 			// We've stumbled into the next token; backtrack
-			g.input.UnreadRune()
+			g.unreadRune(r)
 			break
 		}
 
 		tokenType = runeType
 		tokenBuf.WriteRune(r)
 
+		if unterminatedBrace {
+			err = newPatternError(ErrUnterminatedBrace, g.offset-1, "{", "unterminated brace expression")
+			break
+		} else if openLiteralBrace {
+			g.braces = append(g.braces, openGroup{token: "{", offset: g.offset - 1, literal: true})
+		} else if closeLiteralBrace {
+			g.braces = g.braces[:len(g.braces)-1]
+		}
+
+		if tokenType == tcExtGlobOpen {
+			// Consume the opening parenthesis (which is known to follow)
+			paren, _ := g.readRune()
+			tokenBuf.WriteRune(paren)
+			break
+		}
+
+		if tokenType == tcCapture {
+			// Consume the rest of the capture (which is known to follow), which is yielded as the wildcard it contains
+			name, _ := g.peekCaptureName()
+			tokenType = tcStar
+			for {
+				r, _ := g.readRune()
+				tokenBuf.WriteRune(r)
+				if r == '}' {
+					break
+				} else if r == '*' && bytes.HasSuffix(tokenBuf.Bytes(), []byte("**")) {
+					tokenType = tcGlobStar
+				}
+			}
+			if !isValidCaptureName(name) {
+				err = newPatternError(ErrInvalidCaptureName, g.offset-tokenBuf.Len(), tokenBuf.String(),
+					"invalid capture name \"%s\"", name)
+			}
+			break
+		}
+
+		if tokenType == tcCharClass {
+			// Bracket expressions are consumed whole
+			err = g.parseCharClass(tokenBuf, g.offset-tokenBuf.Len())
+			break
+		}
+
 		if tokenType == tcEscaper ||
 			tokenType == tcGlobStar ||
 			tokenType == tcAny ||
-			tokenType == tcSeparator {
+			tokenType == tcSeparator ||
+			tokenType == tcBraceOpen ||
+			tokenType == tcBraceSeparator ||
+			tokenType == tcBraceClose ||
+			tokenType == tcExtGlobSeparator ||
+			tokenType == tcExtGlobClose {
 			// These tokens are standalone; continued consumption must be a separate token
 			break
 		}
@@ -105,6 +415,21 @@ func (g *globTokeniser) parse(lastTokenType tc) (string, tc, error) {
 	if err == io.EOF && tokenType != tcUnknown {
 		// If we have a token, we can't have an EOF: we want the EOF on the next pass
 		err = nil
+	} else if err == io.EOF && len(g.extGlobs) > 0 {
+		open := g.extGlobs[len(g.extGlobs)-1]
+		err = newPatternError(ErrUnterminatedExtGlob, open.offset, open.token,
+			"unterminated extended glob group (%d unclosed)", len(g.extGlobs))
+	}
+
+	switch tokenType {
+	case tcBraceOpen:
+		g.braces = append(g.braces, openGroup{token: tokenBuf.String(), offset: g.offset - tokenBuf.Len()})
+	case tcBraceClose:
+		g.braces = g.braces[:len(g.braces)-1]
+	case tcExtGlobOpen:
+		g.extGlobs = append(g.extGlobs, openGroup{token: tokenBuf.String(), offset: g.offset - tokenBuf.Len()})
+	case tcExtGlobClose:
+		g.extGlobs = g.extGlobs[:len(g.extGlobs)-1]
 	}
 
 	if err != nil {
@@ -113,12 +438,84 @@ func (g *globTokeniser) parse(lastTokenType tc) (string, tc, error) {
 
 	if tokenType == tcEscaper {
 		// Escapers should never be yielded; recurse to find the next token
-		return g.parse(tokenType)
+		token, tokenType, err := g.parse(tokenType)
+		if err == io.EOF && g.globOptions.Strict {
+			return "", tcUnknown, newPatternError(ErrDanglingEscaper, g.offset-tokenBuf.Len(), tokenBuf.String(),
+				"dangling escaper at end of pattern")
+		}
+		return token, tokenType, err
 	}
 
 	return tokenBuf.String(), tokenType, err
 }
 
+// Consumes the remainder of a bracket expression (the opening [ must already have been consumed) into buf. A ] which
+// appears as the first member of the class is treated literally, as is any escaped character. The offset of the
+// opening [ is used to report an unterminated class.
+func (g *globTokeniser) parseCharClass(buf *bytes.Buffer, offset int) error {
+	members := 0
+	escaped := false
+	for {
+		r, err := g.readRune()
+		if err == io.EOF {
+			return newPatternError(ErrUnterminatedCharClass, offset, buf.String(), "unterminated character class \"%s\"",
+				buf.String())
+		} else if err != nil {
+			return err
+		}
+		buf.WriteRune(r)
+
+		switch {
+		case escaped:
+			escaped = false
+		case r == g.globOptions.escaper():
+			escaped = true
+			continue
+		case members == 0 && buf.Len() == 2 && (r == '!' || r == '^'):
+			// Negation marker
+			continue
+		case r == ']' && members > 0:
+			return nil
+		case r == '[':
+			// A named class (eg. [:alpha:]) is a single member, which may contain a ]
+			if err := g.parseNamedClass(buf, offset); err != nil {
+				return err
+			}
+		}
+		members++
+	}
+}
+
+// If the input continues with the remainder of a named class within a bracket expression (eg. ":alpha:]", the opening
+// [ having already been consumed), consumes it into buf
+func (g *globTokeniser) parseNamedClass(buf *bytes.Buffer, offset int) error {
+	r, err := g.readRune()
+	if err != nil {
+		return nil
+	} else if r != ':' {
+		g.unreadRune(r)
+		return nil
+	}
+	buf.WriteRune(r)
+
+	nameLen := 0
+	for {
+		r, err := g.readRune()
+		if err == io.EOF {
+			return newPatternError(ErrUnterminatedCharClass, offset, buf.String(), "unterminated character class \"%s\"",
+				buf.String())
+		} else if err != nil {
+			return err
+		}
+		buf.WriteRune(r)
+
+		if r == ']' && nameLen > 1 && bytes.HasSuffix(buf.Bytes(), []byte(":]")) {
+			return nil
+		}
+		nameLen++
+	}
+}
+
 // Scan advances the tokeniser to the next token, which will then be available through the Token method. It returns
 // false when the tokenisation stops, either by reaching the end of the input or an error. After Scan returns false,
 // the Err method will return any error that occurred during scanning, except that if it was io.EOF, Err will return
@@ -126,7 +523,9 @@ func (g *globTokeniser) parse(lastTokenType tc) (string, tc, error) {
 func (g *globTokeniser) Scan() bool {
 	if g.hasPeek {
 		g.token, g.tokenType, g.err = g.peekToken, g.peekTokenType, g.peekErr
+		g.tokenOffset = g.peekTokenOffset
 	} else {
+		g.tokenOffset = g.offset
 		g.token, g.tokenType, g.err = g.parse(g.tokenType)
 	}
 
@@ -141,6 +540,7 @@ func (g *globTokeniser) Scan() bool {
 // tokeniser to the peeked token. If there is already a peaked token, it will not advance.
 func (g *globTokeniser) Peek() bool {
 	if !g.hasPeek {
+		g.peekTokenOffset = g.offset
 		g.peekToken, g.peekTokenType, g.peekErr = g.parse(g.tokenType)
 		g.hasPeek = true
 	}
@@ -161,6 +561,11 @@ func (g *globTokeniser) Token() (token string, tokenType tc) {
 	return g.token, g.tokenType
 }
 
+// Offset returns the byte offset of the current token within the input
+func (g *globTokeniser) Offset() int {
+	return g.tokenOffset
+}
+
 // PeekToken returns the peeked token
 func (g *globTokeniser) PeekToken() (token string, tokenType tc) {
 	return g.peekToken, g.peekTokenType
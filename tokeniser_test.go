@@ -128,6 +128,236 @@ func TestTokeniser_Escaper(t *testing.T) {
 	testTokenRun(t, tokeniser, e)
 }
 
+func TestTokeniser_CharClass(t *testing.T) {
+	es := map[string]expectations{
+		`log-[0-9][0-9].txt`: expectations{
+			eToken{`log-`, tcLiteral},
+			eToken{`[0-9]`, tcCharClass},
+			eToken{`[0-9]`, tcCharClass},
+			eToken{`.txt`, tcLiteral},
+		},
+		`[!abc]/[^a-z]`: expectations{
+			eToken{`[!abc]`, tcCharClass},
+			eToken{`/`, tcSeparator},
+			eToken{`[^a-z]`, tcCharClass},
+		},
+		// A ] as the first member is literal, as are escaped members
+		`[]a][!]][\]x]`: expectations{
+			eToken{`[]a]`, tcCharClass},
+			eToken{`[!]]`, tcCharClass},
+			eToken{`[\]x]`, tcCharClass},
+		},
+		`foo\[bar]`: expectations{
+			eToken{`foo`, tcLiteral},
+			eToken{`[bar]`, tcLiteral},
+		},
+		// Named classes may contain a ], which does not close the bracket expression
+		`[[:alpha:]][![:digit:]_]x`: expectations{
+			eToken{`[[:alpha:]]`, tcCharClass},
+			eToken{`[![:digit:]_]`, tcCharClass},
+			eToken{`x`, tcLiteral},
+		},
+		`[[]:]`: expectations{
+			eToken{`[[]`, tcCharClass},
+			eToken{`:]`, tcLiteral},
+		},
+	}
+
+	for input, e := range es {
+		Logger.Tracef("[ohmyglob:TestTokeniser_CharClass] Testing \"%s\"", input)
+		tokeniser := newGlobTokeniser(strings.NewReader(input), DefaultOptions)
+		testTokenRun(t, tokeniser, e)
+	}
+}
+
+func TestTokeniser_Braces(t *testing.T) {
+	es := map[string]expectations{
+		`*.{go,proto}`: expectations{
+			eToken{`*`, tcStar},
+			eToken{`.`, tcLiteral},
+			eToken{`{`, tcBraceOpen},
+			eToken{`go`, tcLiteral},
+			eToken{`,`, tcBraceSeparator},
+			eToken{`proto`, tcLiteral},
+			eToken{`}`, tcBraceClose},
+		},
+		`a,{b,{,c}}d}`: expectations{
+			eToken{`a,`, tcLiteral},
+			eToken{`{`, tcBraceOpen},
+			eToken{`b`, tcLiteral},
+			eToken{`,`, tcBraceSeparator},
+			eToken{`{`, tcBraceOpen},
+			eToken{`,`, tcBraceSeparator},
+			eToken{`c`, tcLiteral},
+			eToken{`}`, tcBraceClose},
+			eToken{`}`, tcBraceClose},
+			eToken{`d}`, tcLiteral},
+		},
+		`\{a\,b}`: expectations{
+			eToken{`{a`, tcLiteral},
+			eToken{`,b}`, tcLiteral},
+		},
+		// Braces which don't open a brace expression are literal, along with their closing braces and commas
+		`{a,{b}}`: expectations{
+			eToken{`{`, tcBraceOpen},
+			eToken{`a`, tcLiteral},
+			eToken{`,`, tcBraceSeparator},
+			eToken{`{b}`, tcLiteral},
+			eToken{`}`, tcBraceClose},
+		},
+		`{a{1..3}}`: expectations{
+			eToken{`{a`, tcLiteral},
+			eToken{`{`, tcBraceOpen},
+			eToken{`1..3`, tcLiteral},
+			eToken{`}`, tcBraceClose},
+			eToken{`}`, tcLiteral},
+		},
+		`{a,{b}`: expectations{
+			eToken{`{a,{b}`, tcLiteral},
+		},
+	}
+
+	for input, e := range es {
+		Logger.Tracef("[ohmyglob:TestTokeniser_Braces] Testing \"%s\"", input)
+		tokeniser := newGlobTokeniser(strings.NewReader(input), DefaultOptions)
+		testTokenRun(t, tokeniser, e)
+	}
+
+	tokeniser := newGlobTokeniser(strings.NewReader(`{a,{b}`), &Options{Separator: '/', Strict: true})
+	for tokeniser.Scan() {
+	}
+	assert.Error(t, tokeniser.Err(), "Unterminated brace expressions should be an error when parsing strictly")
+}
+
+func TestTokeniser_ExtGlob(t *testing.T) {
+	options := &Options{
+		Separator:    '/',
+		MatchAtStart: true,
+		MatchAtEnd:   true,
+		ExtGlob:      true,
+	}
+	es := map[string]expectations{
+		`!(*.test).go`: expectations{
+			eToken{`!(`, tcExtGlobOpen},
+			eToken{`*`, tcStar},
+			eToken{`.test`, tcLiteral},
+			eToken{`)`, tcExtGlobClose},
+			eToken{`.go`, tcLiteral},
+		},
+		`a+(ab|cd)@(x)|)`: expectations{
+			eToken{`a`, tcLiteral},
+			eToken{`+(`, tcExtGlobOpen},
+			eToken{`ab`, tcLiteral},
+			eToken{`|`, tcExtGlobSeparator},
+			eToken{`cd`, tcLiteral},
+			eToken{`)`, tcExtGlobClose},
+			eToken{`@(`, tcExtGlobOpen},
+			eToken{`x`, tcLiteral},
+			eToken{`)`, tcExtGlobClose},
+			eToken{`|)`, tcLiteral},
+		},
+		`**(a)?(b)*`: expectations{
+			eToken{`*`, tcStar},
+			eToken{`*(`, tcExtGlobOpen},
+			eToken{`a`, tcLiteral},
+			eToken{`)`, tcExtGlobClose},
+			eToken{`?(`, tcExtGlobOpen},
+			eToken{`b`, tcLiteral},
+			eToken{`)`, tcExtGlobClose},
+			eToken{`*`, tcStar},
+		},
+		`\+(a)`: expectations{
+			eToken{`+(a)`, tcLiteral},
+		},
+	}
+
+	for input, e := range es {
+		Logger.Tracef("[ohmyglob:TestTokeniser_ExtGlob] Testing \"%s\"", input)
+		tokeniser := newGlobTokeniser(strings.NewReader(input), options)
+		testTokenRun(t, tokeniser, e)
+	}
+
+	// Without the option, extended globs are not recognised
+	tokeniser := newGlobTokeniser(strings.NewReader(`+(a|b)`), DefaultOptions)
+	testTokenRun(t, tokeniser, expectations{
+		eToken{`+(a|b)`, tcLiteral},
+	})
+}
+
+func TestTokeniser_UnterminatedCharClass(t *testing.T) {
+	// As in bash, an opening bracket which isn't closed is literal
+	es := map[string]expectations{
+		`foo[abc`: expectations{
+			eToken{`foo[abc`, tcLiteral},
+		},
+		`[`: expectations{
+			eToken{`[`, tcLiteral},
+		},
+		`[]`: expectations{
+			eToken{`[]`, tcLiteral},
+		},
+		`[!]*`: expectations{
+			eToken{`[!]`, tcLiteral},
+			eToken{`*`, tcStar},
+		},
+		`[a\]`: expectations{
+			eToken{`[a`, tcLiteral},
+			eToken{`]`, tcLiteral},
+		},
+		`[[:alpha]`: expectations{
+			eToken{`[`, tcLiteral},
+			eToken{`[:alpha]`, tcCharClass},
+		},
+		`a[b/c`: expectations{
+			eToken{`a[b`, tcLiteral},
+			eToken{`/`, tcSeparator},
+			eToken{`c`, tcLiteral},
+		},
+	}
+	for input, e := range es {
+		Logger.Tracef("[ohmyglob:TestTokeniser_UnterminatedCharClass] Testing \"%s\"", input)
+		tokeniser := newGlobTokeniser(strings.NewReader(input), DefaultOptions)
+		testTokenRun(t, tokeniser, e)
+	}
+
+	// ...unless parsing strictly
+	strictOptions := &Options{Separator: '/', Strict: true}
+	for _, input := range []string{`foo[abc`, `[`, `[]`, `[!]`, `[a\]`, `[[:alpha]`, `[[:alpha:]`} {
+		tokeniser := newGlobTokeniser(strings.NewReader(input), strictOptions)
+		for tokeniser.Scan() {
+		}
+		assert.Error(t, tokeniser.Err(), "Expected an error tokenising %s", input)
+	}
+}
+
+func TestTokeniser_Offsets(t *testing.T) {
+	input := `∆foo/\*[[:alpha:]]**`
+	tokeniser := newGlobTokeniser(strings.NewReader(input), DefaultOptions)
+
+	// Maps tokens to their expected byte offsets; an escaped token's offset is that of its escaper
+	e := []struct {
+		token  string
+		offset int
+	}{
+		{`∆foo`, 0},
+		{`/`, 6},
+		{`*`, 7},
+		{`[[:alpha:]]`, 9},
+		{`**`, 20},
+	}
+	for i, expected := range e {
+		assert.True(t, tokeniser.Scan())
+		token, _ := tokeniser.Token()
+		assert.Equal(t, expected.token, token)
+		assert.Equal(t, expected.offset, tokeniser.Offset(), "Unexpected offset for token %d", i)
+
+		// Peeking should not affect the offset of the current token
+		tokeniser.Peek()
+		assert.Equal(t, expected.offset, tokeniser.Offset(), "Peek altered offset for token %d", i)
+	}
+	assert.False(t, tokeniser.Scan())
+}
+
 // Test various cominations; we don't just have one giant function because we want to know which individual components
 // are broken, if they are
 func TestTokeniser_Combinations(t *testing.T) {
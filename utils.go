@@ -3,6 +3,7 @@ package ohmyglob
 import (
 	"bufio"
 	"bytes"
+	"io"
 	"regexp"
 	"strings"
 	"unicode/utf8"
@@ -15,11 +16,62 @@ func init() {
 	runesToEscape = make([]rune, len(expanders))
 }
 
+// specialRunes returns the runes that, in addition to the separators, have meaning in a glob compiled with the passed
+// options
+func specialRunes(options *Options) []rune {
+	runes := make([]rune, 0, len(expanders)+5)
+	runes = append(runes, expanders...)
+	runes = append(runes, options.escaper())
+	// Commas have no meaning within brace expressions when they are separators
+	if options.Separator != ',' && options.AltSeparator != ',' {
+		runes = append(runes, ',')
+	}
+	if options.ExtGlob {
+		runes = append(runes, '(', ')', '|')
+	}
+	return runes
+}
+
 // Escapes any characters that would have special meaning in a regular expression, returning the escaped string
 func escapeRegexComponent(str string) string {
 	return escapeNeededCharRegex.ReplaceAllString(str, "\\$0")
 }
 
+// Returns a regular expression component matching the literal string, matching ASCII letters case-insensitively if
+// required by the options (Unicode case folding is applied to the whole expression by regexFlags)
+func literalRegexComponent(str string, options *Options) string {
+	if options.CaseFolding != FoldASCII {
+		return escapeRegexComponent(str)
+	}
+
+	buf := new(bytes.Buffer)
+	for _, r := range str {
+		switch {
+		case r >= 'a' && r <= 'z':
+			buf.WriteRune('[')
+			buf.WriteRune(r - 'a' + 'A')
+			buf.WriteRune(r)
+			buf.WriteRune(']')
+		case r >= 'A' && r <= 'Z':
+			buf.WriteRune('[')
+			buf.WriteRune(r)
+			buf.WriteRune(r - 'A' + 'a')
+			buf.WriteRune(']')
+		default:
+			buf.WriteString(escapeRegexComponent(string(r)))
+		}
+	}
+	return buf.String()
+}
+
+// Returns the flags that should prefix any regular expression compiled with the passed options
+func regexFlags(options *Options) string {
+	if options.CaseFolding == FoldUnicode {
+		return "(?i)"
+	}
+	return ""
+}
+
 // separatorsScanner returns a split function for a scanner that returns tokens delimited any of the specified runes.
 // Note that the delimiters themselves are counted as tokens, so callers who want to discard the separators must do this
 // themselves.
@@ -66,9 +118,13 @@ func EscapeGlobComponent(component string, options *Options) string {
 		options = DefaultOptions
 	}
 
-	runesToEscape := make([]rune, 0, len(expanders)+1)
-	runesToEscape = append(runesToEscape, expanders...)
+	special := specialRunes(options)
+	runesToEscape := make([]rune, 0, len(special)+2)
+	runesToEscape = append(runesToEscape, special...)
 	runesToEscape = append(runesToEscape, options.Separator)
+	if options.AltSeparator != 0 {
+		runesToEscape = append(runesToEscape, options.AltSeparator)
+	}
 
 	runesToEscapeMap := make(map[string]bool, len(runesToEscape))
 	for _, r := range runesToEscape {
@@ -81,7 +137,7 @@ func EscapeGlobComponent(component string, options *Options) string {
 	for scanner.Scan() {
 		component := scanner.Text()
 		if runesToEscapeMap[component] {
-			buf.WriteRune(Escaper)
+			buf.WriteRune(options.escaper())
 		}
 		buf.WriteString(component)
 	}
@@ -96,21 +152,54 @@ func EscapeGlobString(gs string, options *Options) string {
 		options = DefaultOptions
 	}
 
-	runesToEscapeMap := make(map[string]bool, len(expanders))
-	for _, r := range expanders {
+	special := specialRunes(options)
+	runesToEscapeMap := make(map[string]bool, len(special))
+	for _, r := range special {
 		runesToEscapeMap[string(r)] = true
 	}
 
 	scanner := bufio.NewScanner(strings.NewReader(gs))
-	scanner.Split(separatorsScanner(expanders))
+	scanner.Split(separatorsScanner(special))
 	buf := new(bytes.Buffer)
 	for scanner.Scan() {
 		part := scanner.Text()
 		if runesToEscapeMap[part] {
-			buf.WriteRune(Escaper)
+			buf.WriteRune(options.escaper())
 		}
 		buf.WriteString(part)
 	}
 
 	return buf.String()
 }
+
+// Drains a RuneReader into a string, failing with ErrInputTooLong if the input is longer than maxLength bytes (0 means
+// no limit). A RuneReader which is also an io.Reader has its bytes read directly, so that invalid UTF-8 reaches the
+// matcher exactly as it would through MatchString, rather than being replaced with utf8.RuneError.
+func readAll(r io.RuneReader, maxLength int) (string, error) {
+	if reader, ok := r.(io.Reader); ok {
+		if maxLength > 0 {
+			reader = io.LimitReader(reader, int64(maxLength)+1)
+		}
+		b, err := io.ReadAll(reader)
+		if err != nil {
+			return "", err
+		} else if maxLength > 0 && len(b) > maxLength {
+			return "", ErrInputTooLong
+		}
+		return string(b), nil
+	}
+
+	buf := new(strings.Builder)
+	for {
+		rn, _, err := r.ReadRune()
+		if err == io.EOF {
+			return buf.String(), nil
+		} else if err != nil {
+			return "", err
+		}
+		buf.WriteRune(rn)
+		if maxLength > 0 && buf.Len() > maxLength {
+			return "", ErrInputTooLong
+		}
+	}
+}
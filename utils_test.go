@@ -66,11 +66,23 @@ func TestEscapeGlobComponent(t *testing.T) {
 		`\foobar`:                `\\foobar`,
 		`/∆≈¨´∂#ª˙ƒ¨∞˙**®´∆¢#º....///..∂˚ø´∂˚®≥...`: `\/∆≈¨´∂#ª˙ƒ¨∞˙\*\*®´∆¢#º....\/\/\/..∂˚ø´∂˚®≥...`,
 		`!ADMIN`: `\!ADMIN`,
+		`[abc]`:  `\[abc\]`,
 	}
 
 	for src, result := range expectations {
 		assert.Equal(t, result, EscapeGlobComponent(src, DefaultOptions))
 	}
+
+	// Extended glob syntax is only escaped when it is enabled
+	extGlobOptions := &Options{
+		Separator: '/',
+		ExtGlob:   true,
+	}
+	assert.Equal(t, `+\(a\|b\)`, EscapeGlobComponent(`+(a|b)`, extGlobOptions))
+	assert.Equal(t, `+(a|b)`, EscapeGlobComponent(`+(a|b)`, DefaultOptions))
+
+	// Both separators are escaped, using the Escaper from the options
+	assert.Equal(t, "a`\\b`/c`*`[d`]``", EscapeGlobComponent("a\\b/c*[d]`", WindowsOptions))
 }
 
 func TestEscapeGlobString(t *testing.T) {
@@ -86,4 +98,5 @@ func TestEscapeGlobString(t *testing.T) {
 	for src, result := range expectations {
 		assert.Equal(t, result, EscapeGlobString(src, DefaultOptions))
 	}
+	assert.Equal(t, "C:\\Program Files/`[x86`]\\`*", EscapeGlobString(`C:\Program Files/[x86]\*`, WindowsOptions))
 }